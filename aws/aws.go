@@ -5,15 +5,26 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/client"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/ecr"
 	"github.com/aws/aws-sdk-go/service/ecs"
 )
 
+const (
+	// maxDescribeBatch is the maximum of resources accepted by ECS describe calls.
+	maxDescribeBatch = 100
+	// maxDescribeServicesBatch is the maximum of services accepted by DescribeServices.
+	maxDescribeServicesBatch = 10
+	// maxConcurrentDescribes keeps concurrent calls below ECS request rate limit.
+	maxConcurrentDescribes = 8
+)
+
 func checkErr(method string, err error) {
 	if err != nil {
 		fmt.Printf("Cannot call %s: %s\n", method, err)
@@ -21,10 +32,28 @@ func checkErr(method string, err error) {
 	}
 }
 
+func chunk(values []string, size int) [][]string {
+	chunks := make([][]string, 0, len(values)/size+1)
+
+	for len(values) > size {
+		chunks = append(chunks, values[:size])
+		values = values[size:]
+	}
+	if len(values) > 0 {
+		chunks = append(chunks, values)
+	}
+
+	return chunks
+}
+
 func getRevisionFromTaskDefinition(arn string) string {
 	return arn[strings.LastIndex(arn, ":")+1:]
 }
 
+func getFamilyFromTaskDefinition(arn string) string {
+	return arn[strings.LastIndex(arn, "/")+1 : strings.LastIndex(arn, ":")]
+}
+
 func formatUptime(d time.Duration) string {
 	var result string
 
@@ -67,42 +96,57 @@ func DescribeRepository(sess client.ConfigProvider, service string) *ecr.Reposit
 	return resp.Repositories[0]
 }
 
-func RegisterTaskDefinition(client client.ConfigProvider, service string, containerDefinitions []*ecs.ContainerDefinition) *ecs.TaskDefinition {
-	svc := ecs.New(client)
+// RegisterTaskDefinition creates a new revision of taskDefinition, every
+// task level attribute is kept, not just the container definitions.
+func RegisterTaskDefinition(client client.ConfigProvider, taskDefinition *ecs.TaskDefinition) *ecs.TaskDefinition {
+	return RegisterTaskDefinitionInput(client, NewRegisterTaskDefinitionInput(taskDefinition))
+}
 
-	params := &ecs.RegisterTaskDefinitionInput{
-		ContainerDefinitions: containerDefinitions,
-		Family:               aws.String(service),
-	}
+func RegisterTaskDefinitionInput(client client.ConfigProvider, params *ecs.RegisterTaskDefinitionInput) *ecs.TaskDefinition {
+	svc := ecs.New(client)
 
 	resp, err := svc.RegisterTaskDefinition(params)
 	checkErr("RegisterTaskDefinition", err)
 
-	fmt.Printf("New revision to '%s' was created, number: %d\n", service, *resp.TaskDefinition.Revision)
+	fmt.Printf("New revision to '%s' was created, number: %d\n", *resp.TaskDefinition.Family, *resp.TaskDefinition.Revision)
 
 	return resp.TaskDefinition
 }
 
 func ListTaskDefinitions(client client.ConfigProvider, service string, limit int64) []string {
+	return ListTaskDefinitionsByStatus(client, service, ecs.TaskDefinitionStatusActive, limit)
+}
+
+// ListTaskDefinitionsByStatus returns the newest revisions of service first,
+// following pagination until limit is reached (zero means all).
+func ListTaskDefinitionsByStatus(client client.ConfigProvider, service, status string, limit int64) []string {
 	fmt.Printf("# Listing task definitions of '%s'...\n", service)
 
 	svc := ecs.New(client)
 
 	params := &ecs.ListTaskDefinitionsInput{
 		FamilyPrefix: aws.String(service),
+		Status:       aws.String(status),
 		Sort:         aws.String("DESC"),
 	}
-	if limit > 0 {
+	if limit > 0 && limit <= 100 {
 		params.MaxResults = aws.Int64(limit)
 	}
 
-	resp, err := svc.ListTaskDefinitions(params)
-	checkErr("ListTaskDefinitions", err)
+	arns := make([]string, 0)
 
-	arns := make([]string, len(resp.TaskDefinitionArns))
-	for k, arn := range resp.TaskDefinitionArns {
-		arns[k] = *arn
-	}
+	err := svc.ListTaskDefinitionsPages(params, func(resp *ecs.ListTaskDefinitionsOutput, lastPage bool) bool {
+		for _, arn := range resp.TaskDefinitionArns {
+			if limit > 0 && int64(len(arns)) >= limit {
+				return false
+			}
+
+			arns = append(arns, *arn)
+		}
+
+		return limit <= 0 || int64(len(arns)) < limit
+	})
+	checkErr("ListTaskDefinitions", err)
 
 	return arns
 }
@@ -110,21 +154,21 @@ func ListTaskDefinitions(client client.ConfigProvider, service string, limit int
 func DescribeTasks(client client.ConfigProvider, cluster string, taskIDs []string) []*ecs.Task {
 	svc := ecs.New(client)
 
-	params := &ecs.DescribeTasksInput{
-		Cluster: aws.String(cluster),
-		Tasks: func(tasks []string) []*string {
-			tasksIDs := make([]*string, len(tasks))
-			for k, v := range tasks {
-				tasksIDs[k] = aws.String(v)
-			}
-			return tasksIDs
-		}(taskIDs),
-	}
+	tasks := make([]*ecs.Task, 0, len(taskIDs))
 
-	resp, err := svc.DescribeTasks(params)
-	checkErr("DescribeTasks", err)
+	for _, ids := range chunk(taskIDs, maxDescribeBatch) {
+		params := &ecs.DescribeTasksInput{
+			Cluster: aws.String(cluster),
+			Tasks:   aws.StringSlice(ids),
+		}
 
-	return resp.Tasks
+		resp, err := svc.DescribeTasks(params)
+		checkErr("DescribeTasks", err)
+
+		tasks = append(tasks, resp.Tasks...)
+	}
+
+	return tasks
 }
 
 func DescribeTasksByService(client client.ConfigProvider, cluster, service string, showAll bool) []*ecs.Task {
@@ -159,6 +203,132 @@ func DescribeTaskDefinition(client client.ConfigProvider, service string, revisi
 	return resp.TaskDefinition
 }
 
+// describeTaskDefinitionIfExists returns nil when family has no revision,
+// other errors stop the command as in DescribeTaskDefinition.
+func describeTaskDefinitionIfExists(client client.ConfigProvider, family string) *ecs.TaskDefinition {
+	svc := ecs.New(client)
+
+	resp, err := svc.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{
+		TaskDefinition: aws.String(family),
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == ecs.ErrCodeClientException &&
+		strings.Contains(strings.ToLower(aerr.Message()), "unable to describe task definition") {
+		return nil
+	}
+	checkErr("DescribeTaskDefinition", err)
+
+	return resp.TaskDefinition
+}
+
+// DescribeTaskDefinitions describes every ARN, up to maxConcurrentDescribes
+// calls at the same time since ECS has no batch call for task definitions.
+func DescribeTaskDefinitions(client client.ConfigProvider, arns []string) (map[string]*ecs.DescribeTaskDefinitionOutput, error) {
+	svc := ecs.New(client)
+
+	var (
+		wg       sync.WaitGroup
+		mutex    sync.Mutex
+		firstErr error
+	)
+
+	taskDefinitions := make(map[string]*ecs.DescribeTaskDefinitionOutput, len(arns))
+	queue := make(chan string)
+
+	for i := 0; i < maxConcurrentDescribes; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for arn := range queue {
+				resp, err := svc.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{
+					TaskDefinition: aws.String(arn),
+					Include:        aws.StringSlice([]string{ecs.TaskDefinitionFieldTags}),
+				})
+
+				mutex.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+					}
+				} else {
+					taskDefinitions[arn] = resp
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	for _, arn := range arns {
+		queue <- arn
+	}
+
+	close(queue)
+	wg.Wait()
+
+	return taskDefinitions, firstErr
+}
+
+func DeregisterTaskDefinition(client client.ConfigProvider, arn string) {
+	svc := ecs.New(client)
+
+	_, err := svc.DeregisterTaskDefinition(&ecs.DeregisterTaskDefinitionInput{
+		TaskDefinition: aws.String(arn),
+	})
+	checkErr("DeregisterTaskDefinition", err)
+}
+
+func ListClusters(client client.ConfigProvider) []string {
+	svc := ecs.New(client)
+
+	clusters := make([]string, 0)
+
+	err := svc.ListClustersPages(&ecs.ListClustersInput{}, func(resp *ecs.ListClustersOutput, lastPage bool) bool {
+		for _, arn := range resp.ClusterArns {
+			clusters = append(clusters, *arn)
+		}
+		return true
+	})
+	checkErr("ListClusters", err)
+
+	return clusters
+}
+
+// ListServices returns the ARN of every service of cluster.
+func ListServices(client client.ConfigProvider, cluster string) []string {
+	svc := ecs.New(client)
+
+	services := make([]string, 0)
+
+	err := svc.ListServicesPages(&ecs.ListServicesInput{Cluster: aws.String(cluster)}, func(resp *ecs.ListServicesOutput, lastPage bool) bool {
+		services = append(services, aws.StringValueSlice(resp.ServiceArns)...)
+		return true
+	})
+	checkErr("ListServices", err)
+
+	return services
+}
+
+// DescribeServices returns the services found in cluster, missing ones are
+// ignored.
+func DescribeServices(client client.ConfigProvider, cluster string, services []string) []*ecs.Service {
+	svc := ecs.New(client)
+
+	result := make([]*ecs.Service, 0, len(services))
+
+	for _, batch := range chunk(services, maxDescribeServicesBatch) {
+		resp, err := svc.DescribeServices(&ecs.DescribeServicesInput{
+			Cluster:  aws.String(cluster),
+			Services: aws.StringSlice(batch),
+		})
+		checkErr("DescribeServices", err)
+
+		result = append(result, resp.Services...)
+	}
+
+	return result
+}
+
 func ListRunningTasks(client client.ConfigProvider, cluster, service string) []string {
 	svc := ecs.New(client)
 
@@ -168,13 +338,13 @@ func ListRunningTasks(client client.ConfigProvider, cluster, service string) []s
 		DesiredStatus: aws.String("RUNNING"),
 	}
 
-	resp, err := svc.ListTasks(params)
-	checkErr("ListTasks", err)
+	tasks := make([]string, 0)
 
-	tasks := make([]string, len(resp.TaskArns))
-	for k, taskArn := range resp.TaskArns {
-		tasks[k] = *taskArn
-	}
+	err := svc.ListTasksPages(params, func(resp *ecs.ListTasksOutput, lastPage bool) bool {
+		tasks = append(tasks, aws.StringValueSlice(resp.TaskArns)...)
+		return true
+	})
+	checkErr("ListTasks", err)
 
 	return tasks
 }
@@ -200,31 +370,56 @@ func ListStoppedTasks(client client.ConfigProvider, cluster, service string) []s
 	return tasks
 }
 
+// DescribeContainerInstances returns the EC2 instance behind a container
+// instance.
 func DescribeContainerInstances(client client.ConfigProvider, cluster, containerInstanceArn string) (*ec2.Instance, error) {
-	ecsSvc := ecs.New(client)
+	instances, err := DescribeInstancesOfContainerInstances(client, cluster, []string{containerInstanceArn})
+	if err != nil {
+		return nil, err
+	}
 
-	params := &ecs.DescribeContainerInstancesInput{
-		Cluster: aws.String(cluster),
-		ContainerInstances: []*string{
-			aws.String(containerInstanceArn),
-		},
+	instance, ok := instances[containerInstanceArn]
+	if !ok {
+		return nil, errors.New("no instaces was found")
 	}
 
-	resp, err := ecsSvc.DescribeContainerInstances(params)
-	checkErr("DescribeContainerInstances", err)
+	return instance, nil
+}
+
+// DescribeInstancesOfContainerInstances returns the EC2 instance of each
+// container instance indexed by its ARN, ECS and EC2 are called once for each
+// batch of instances instead of once per instance.
+func DescribeInstancesOfContainerInstances(client client.ConfigProvider, cluster string, containerInstanceArns []string) (map[string]*ec2.Instance, error) {
+	ecsSvc := ecs.New(client)
+	ec2Svc := ec2.New(client)
+
+	// container instance arn indexed by ec2 instance id
+	containerInstances := make(map[string]string, len(containerInstanceArns))
 
-	if len(resp.ContainerInstances) == 0 {
-		fmt.Println("Cannot find any instance to", containerInstanceArn)
-		os.Exit(1)
+	for _, arns := range chunk(containerInstanceArns, maxDescribeBatch) {
+		params := &ecs.DescribeContainerInstancesInput{
+			Cluster:            aws.String(cluster),
+			ContainerInstances: aws.StringSlice(arns),
+		}
+
+		resp, err := ecsSvc.DescribeContainerInstances(params)
+		checkErr("DescribeContainerInstances", err)
+
+		for _, containerInstance := range resp.ContainerInstances {
+			containerInstances[*containerInstance.Ec2InstanceId] = *containerInstance.ContainerInstanceArn
+		}
+	}
+
+	instanceIDs := make([]string, 0, len(containerInstances))
+	for instanceID := range containerInstances {
+		instanceIDs = append(instanceIDs, instanceID)
 	}
 
-	for _, containerInstance := range resp.ContainerInstances {
-		ec2Svc := ec2.New(client)
+	instances := make(map[string]*ec2.Instance, len(containerInstances))
 
+	for _, ids := range chunk(instanceIDs, maxDescribeBatch) {
 		params := &ec2.DescribeInstancesInput{
-			InstanceIds: []*string{
-				containerInstance.Ec2InstanceId,
-			},
+			InstanceIds: aws.StringSlice(ids),
 		}
 
 		resp, err := ec2Svc.DescribeInstances(params)
@@ -232,15 +427,12 @@ func DescribeContainerInstances(client client.ConfigProvider, cluster, container
 			return nil, err
 		}
 
-		if len(resp.Reservations) == 0 {
-			return nil, errors.New("no instaces was found in 'reservation' field")
-		}
-		if len(resp.Reservations[0].Instances) == 0 {
-			return nil, errors.New("no instaces was found")
+		for _, reservation := range resp.Reservations {
+			for _, instance := range reservation.Instances {
+				instances[containerInstances[*instance.InstanceId]] = instance
+			}
 		}
-
-		return resp.Reservations[0].Instances[0], nil
 	}
 
-	return nil, nil
+	return instances, nil
 }
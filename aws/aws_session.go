@@ -16,6 +16,9 @@ import (
 type AWSSession struct {
 	Client      client.ConfigProvider
 	Environment *deploy.Environment
+	// Masker hides sensitive values of env vars, NewMasker(nil, true) is used
+	// when it's nil.
+	Masker *Masker
 }
 
 func NewAWSSession(env *deploy.Environment) *AWSSession {
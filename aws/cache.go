@@ -2,20 +2,39 @@ package aws
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/NeowayLabs/logger"
 	deploy "github.com/guilherme-santos/deploy-ecs"
 )
 
+// CacheTTL is how long a task is cached, after that its host and containers
+// are looked up again.
+const CacheTTL = 12 * time.Hour
+
 type (
+	// CacheEntry is where a task runs, to avoid calling ECS, EC2 and the ECS
+	// agent every time logs or exec are used.
 	CacheEntry struct {
 		RemoteHost           string
 		ContainerInstanceArn string
 		TaskArn              string
 		Containers           []deploy.Container
+		CachedAt             time.Time
+	}
+
+	// CachedTask is an entry of the cache with the cluster it belongs to.
+	CachedTask struct {
+		Region      string
+		ClusterName string
+		TaskID      string
+		Entry       CacheEntry
 	}
 )
 
@@ -37,25 +56,181 @@ func (entry CacheEntry) HasContainer() bool {
 	return true
 }
 
-func GetTaskFromCache(taskID string) CacheEntry {
-	filename := filepath.Join(os.TempDir(), "deploy-ecs", taskID+".json")
+func (entry CacheEntry) IsExpired() bool {
+	return time.Since(entry.CachedAt) > CacheTTL
+}
+
+// GetCacheDir returns where tasks are cached, in the cache directory of the
+// user (e.g. ~/.cache/deploy-ecs/tasks).
+func GetCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+
+	return filepath.Join(dir, "deploy-ecs", "tasks")
+}
 
+// getClusterCacheDir returns the directory of the cluster of env, task ids
+// are only unique inside of a cluster.
+func getClusterCacheDir(env *deploy.Environment) string {
+	return filepath.Join(GetCacheDir(), env.Region, env.ClusterName)
+}
+
+func getTaskCacheFilename(env *deploy.Environment, taskID string) string {
+	return filepath.Join(getClusterCacheDir(env), taskID+".json")
+}
+
+func readCacheEntry(filename string) (CacheEntry, error) {
 	var entry CacheEntry
 
 	content, err := ioutil.ReadFile(filename)
 	if err != nil {
-		return entry
+		return entry, err
+	}
+
+	err = json.Unmarshal(content, &entry)
+	return entry, err
+}
+
+// GetTaskFromCache returns taskID of the cluster of env, an empty entry is
+// returned when it's not cached or expired.
+func GetTaskFromCache(env *deploy.Environment, taskID string) CacheEntry {
+	filename := getTaskCacheFilename(env, taskID)
+
+	entry, err := readCacheEntry(filename)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Warn("Ignoring cache of task %s: %s", taskID, err)
+			os.Remove(filename)
+		}
+
+		return CacheEntry{}
+	}
+
+	if entry.IsExpired() {
+		os.Remove(filename)
+		return CacheEntry{}
 	}
 
-	json.Unmarshal(content, &entry)
 	return entry
 }
 
-func SaveTaskToCache(taskID string, entry CacheEntry) {
-	tempDir := filepath.Join(os.TempDir(), "deploy-ecs")
-	os.MkdirAll(tempDir, 0755)
+// SaveTaskToCache writes entry to a temporary file renamed to its name, so a
+// command running at the same time never reads it half written.
+func SaveTaskToCache(env *deploy.Environment, taskID string, entry CacheEntry) {
+	dir := getClusterCacheDir(env)
+
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		logger.Warn("Cannot cache task %s: %s", taskID, err)
+		return
+	}
+
+	if entry.CachedAt.IsZero() {
+		entry.CachedAt = time.Now()
+	}
+
+	content, err := json.Marshal(entry)
+	if err != nil {
+		logger.Warn("Cannot cache task %s: %s", taskID, err)
+		return
+	}
+
+	file, err := ioutil.TempFile(dir, taskID+".*.tmp")
+	if err != nil {
+		logger.Warn("Cannot cache task %s: %s", taskID, err)
+		return
+	}
+
+	_, err = file.Write(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), getTaskCacheFilename(env, taskID))
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		logger.Warn("Cannot cache task %s: %s", taskID, err)
+	}
+}
+
+// InvalidateTaskCache removes taskID from the cache, e.g. when its host
+// cannot be reached anymore.
+func InvalidateTaskCache(env *deploy.Environment, taskID string) {
+	err := os.Remove(getTaskCacheFilename(env, taskID))
+	if err != nil && !os.IsNotExist(err) {
+		logger.Warn("Cannot remove cache of task %s: %s", taskID, err)
+	}
+}
+
+// ListTaskCache returns the cached tasks of the cluster of env, or of every
+// cluster when env is nil.
+func ListTaskCache(env *deploy.Environment) []CachedTask {
+	pattern := filepath.Join(GetCacheDir(), "*", "*", "*.json")
+	if env != nil {
+		pattern = filepath.Join(getClusterCacheDir(env), "*.json")
+	}
+
+	filenames, _ := filepath.Glob(pattern)
+	sort.Strings(filenames)
+
+	tasks := make([]CachedTask, 0, len(filenames))
+	for _, filename := range filenames {
+		entry, err := readCacheEntry(filename)
+		if err != nil {
+			continue
+		}
+
+		clusterDir := filepath.Dir(filename)
+		tasks = append(tasks, CachedTask{
+			Region:      filepath.Base(filepath.Dir(clusterDir)),
+			ClusterName: filepath.Base(clusterDir),
+			TaskID:      strings.TrimSuffix(filepath.Base(filename), ".json"),
+			Entry:       entry,
+		})
+	}
+
+	return tasks
+}
+
+// PrintTaskCache shows the cached tasks of the cluster of env, or of every
+// cluster when env is nil.
+func PrintTaskCache(env *deploy.Environment) {
+	tasks := ListTaskCache(env)
+	if len(tasks) == 0 {
+		fmt.Println("No task is cached")
+		return
+	}
+
+	fmt.Println("REGION      CLUSTER                TASK ID                                  AGE        PUBLIC DNS")
+
+	for _, task := range tasks {
+		age := formatUptime(time.Since(task.Entry.CachedAt))
+		if task.Entry.IsExpired() {
+			age = "EXPIRED"
+		}
+
+		fmt.Printf("%-10s  %-20s   %-38s   %-8s   %s\n", task.Region, task.ClusterName, task.TaskID, age, task.Entry.RemoteHost)
+	}
+}
+
+// ClearTaskCache removes the cached tasks of the cluster of env, or the whole
+// cache when env is nil.
+func ClearTaskCache(env *deploy.Environment) {
+	dir := GetCacheDir()
+	if env != nil {
+		dir = getClusterCacheDir(env)
+	}
+
+	count := len(ListTaskCache(env))
+
+	err := os.RemoveAll(dir)
+	if err != nil {
+		fmt.Printf("Cannot clear cache '%s': %s\n", dir, err)
+		os.Exit(1)
+	}
 
-	filename := filepath.Join(tempDir, taskID+".json")
-	content, _ := json.Marshal(entry)
-	ioutil.WriteFile(filename, content, 0644)
+	fmt.Printf("%d cached task(s) removed from '%s'\n", count, dir)
 }
@@ -0,0 +1,527 @@
+package aws
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	yaml "gopkg.in/yaml.v3"
+)
+
+type (
+	// ComposeFile is a docker-compose.yml following the Compose Specification
+	// (docker compose v2), only attributes used by task definitions are here.
+	ComposeFile struct {
+		Services map[string]*ComposeService `yaml:"services"`
+		Volumes  map[string]*ComposeVolume  `yaml:"volumes,omitempty"`
+		// Extra has attributes not known by this tool, e.g. networks
+		Extra map[string]interface{} `yaml:",inline"`
+
+		// warnings of ReadComposeFile, e.g. variables not set
+		warnings composeWarnings
+	}
+
+	ComposeService struct {
+		Image           string                   `yaml:"image,omitempty"`
+		Entrypoint      ComposeCommand           `yaml:"entrypoint,omitempty"`
+		Command         ComposeCommand           `yaml:"command,omitempty"`
+		WorkingDir      string                   `yaml:"working_dir,omitempty"`
+		User            string                   `yaml:"user,omitempty"`
+		Hostname        string                   `yaml:"hostname,omitempty"`
+		Environment     ComposeEnvironment       `yaml:"environment,omitempty"`
+		EnvFile         ComposeEnvFiles          `yaml:"env_file,omitempty"`
+		Ports           ComposePorts             `yaml:"ports,omitempty"`
+		NetworkMode     string                   `yaml:"network_mode,omitempty"`
+		Links           []string                 `yaml:"links,omitempty"`
+		DependsOn       ComposeDependsOnMap      `yaml:"depends_on,omitempty"`
+		CPUShares       int64                    `yaml:"cpu_shares,omitempty"`
+		CPUs            string                   `yaml:"cpus,omitempty"`
+		MemLimit        string                   `yaml:"mem_limit,omitempty"`
+		MemReservation  string                   `yaml:"mem_reservation,omitempty"`
+		ShmSize         string                   `yaml:"shm_size,omitempty"`
+		Volumes         ComposeMounts            `yaml:"volumes,omitempty"`
+		VolumesFrom     []string                 `yaml:"volumes_from,omitempty"`
+		Tmpfs           ComposeStringList        `yaml:"tmpfs,omitempty"`
+		Devices         []string                 `yaml:"devices,omitempty"`
+		Healthcheck     *ComposeHealthcheck      `yaml:"healthcheck,omitempty"`
+		Ulimits         map[string]ComposeUlimit `yaml:"ulimits,omitempty"`
+		Sysctls         ComposeMapping           `yaml:"sysctls,omitempty"`
+		Labels          ComposeMapping           `yaml:"labels,omitempty"`
+		ExtraHosts      []string                 `yaml:"extra_hosts,omitempty"`
+		DNS             ComposeStringList        `yaml:"dns,omitempty"`
+		DNSSearch       ComposeStringList        `yaml:"dns_search,omitempty"`
+		CapAdd          []string                 `yaml:"cap_add,omitempty"`
+		CapDrop         []string                 `yaml:"cap_drop,omitempty"`
+		SecurityOpt     []string                 `yaml:"security_opt,omitempty"`
+		Pid             string                   `yaml:"pid,omitempty"`
+		Ipc             string                   `yaml:"ipc,omitempty"`
+		Init            bool                     `yaml:"init,omitempty"`
+		Privileged      bool                     `yaml:"privileged,omitempty"`
+		ReadOnly        bool                     `yaml:"read_only,omitempty"`
+		StdinOpen       bool                     `yaml:"stdin_open,omitempty"`
+		Tty             bool                     `yaml:"tty,omitempty"`
+		StopGracePeriod string                   `yaml:"stop_grace_period,omitempty"`
+		Deploy          *ComposeDeploy           `yaml:"deploy,omitempty"`
+		// Extra has attributes not known by this tool
+		Extra map[string]interface{} `yaml:",inline"`
+	}
+
+	// ComposeCommand is a command in exec form, e.g. ["npm", "start"].
+	ComposeCommand []string
+
+	// ComposeEnvironment has env vars of a service, variables without value
+	// (read from the shell) are "${KEY}".
+	ComposeEnvironment map[string]string
+
+	// ComposeMapping is a map written as a list of key=value or as a map.
+	ComposeMapping map[string]string
+
+	// ComposeStringList is a list which can be a single string.
+	ComposeStringList []string
+
+	ComposeEnvFiles []string
+
+	// ComposePorts and ComposeMounts are always in the short syntax, e.g.
+	// "8080:80" and "data:/data:ro".
+	ComposePorts  []string
+	ComposeMounts []string
+
+	ComposeDependsOnMap map[string]ComposeDependsOn
+
+	ComposeDependsOn struct {
+		Condition string `yaml:"condition"`
+	}
+
+	ComposeHealthcheck struct {
+		Test        ComposeHealthcheckTest `yaml:"test"`
+		Interval    string                 `yaml:"interval,omitempty"`
+		Timeout     string                 `yaml:"timeout,omitempty"`
+		Retries     int64                  `yaml:"retries,omitempty"`
+		StartPeriod string                 `yaml:"start_period,omitempty"`
+		Disable     bool                   `yaml:"disable,omitempty"`
+	}
+
+	// ComposeHealthcheckTest is in exec form, a string is ["CMD-SHELL", test].
+	ComposeHealthcheckTest []string
+
+	ComposeUlimit struct {
+		Soft int64 `yaml:"soft"`
+		Hard int64 `yaml:"hard"`
+	}
+
+	ComposeDeploy struct {
+		Resources struct {
+			Limits       ComposeResources `yaml:"limits,omitempty"`
+			Reservations ComposeResources `yaml:"reservations,omitempty"`
+		} `yaml:"resources,omitempty"`
+		Extra map[string]interface{} `yaml:",inline"`
+	}
+
+	ComposeResources struct {
+		CPUs   string `yaml:"cpus,omitempty"`
+		Memory string `yaml:"memory,omitempty"`
+	}
+
+	ComposeVolume struct {
+		Driver     string            `yaml:"driver,omitempty"`
+		DriverOpts map[string]string `yaml:"driver_opts,omitempty"`
+		Labels     map[string]string `yaml:"labels,omitempty"`
+		External   bool              `yaml:"external,omitempty"`
+	}
+)
+
+// IsComposeFile returns true to docker-compose.yml, compose.yaml and their
+// variations as docker-compose.override.yml.
+func IsComposeFile(filename string) bool {
+	base := strings.ToLower(filepath.Base(filename))
+	ext := filepath.Ext(base)
+
+	if ext != ".yml" && ext != ".yaml" {
+		return false
+	}
+
+	return strings.HasPrefix(base, "docker-compose") || strings.HasPrefix(base, "compose.")
+}
+
+// ECS dependency conditions and their equivalent in compose.
+var composeConditions = map[string]string{
+	ecs.ContainerConditionStart:    "service_started",
+	ecs.ContainerConditionHealthy:  "service_healthy",
+	ecs.ContainerConditionSuccess:  "service_completed_successfully",
+	ecs.ContainerConditionComplete: "service_completed_successfully",
+}
+
+func formatSeconds(seconds *int64) string {
+	if seconds == nil {
+		return ""
+	}
+
+	return fmt.Sprintf("%ds", *seconds)
+}
+
+func formatMiB(mib *int64) string {
+	if mib == nil || *mib == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("%dm", *mib)
+}
+
+func formatPortMapping(portMapping *ecs.PortMapping, networkMode string) string {
+	containerPort := aws.Int64Value(portMapping.ContainerPort)
+	hostPort := aws.Int64Value(portMapping.HostPort)
+
+	var port string
+
+	switch {
+	case networkMode == ecs.NetworkModeAwsvpc || networkMode == ecs.NetworkModeHost:
+		port = fmt.Sprintf("%d:%d", containerPort, containerPort)
+	case hostPort == 0:
+		// Dynamic port, docker chooses one as ECS does
+		port = fmt.Sprint(containerPort)
+	default:
+		port = fmt.Sprintf("%d:%d", hostPort, containerPort)
+	}
+
+	if protocol := aws.StringValue(portMapping.Protocol); !strings.EqualFold("", protocol) && protocol != ecs.TransportProtocolTcp {
+		port += "/" + protocol
+	}
+
+	return port
+}
+
+// escapeCompose escapes $ as $$, otherwise compose replaces $VAR and ${VAR}
+// by variables of the shell.
+func escapeCompose(value string) string {
+	return strings.Replace(value, "$", "$$", -1)
+}
+
+func escapeComposeSlice(values []*string) []string {
+	escaped := make([]string, 0, len(values))
+	for _, value := range values {
+		escaped = append(escaped, escapeCompose(aws.StringValue(value)))
+	}
+
+	return escaped
+}
+
+func escapeComposeMap(values map[string]string) map[string]string {
+	for key, value := range values {
+		values[key] = escapeCompose(value)
+	}
+
+	return values
+}
+
+// composeWarnings collects what cannot be exported, with the container (or
+// task) it came from.
+type composeWarnings []string
+
+func (warnings *composeWarnings) add(where, format string, args ...interface{}) {
+	*warnings = append(*warnings, fmt.Sprintf("%s: %s", where, fmt.Sprintf(format, args...)))
+}
+
+// NewComposeFile converts taskDefinition to a compose file, what cannot be
+// converted is returned as warnings.
+func NewComposeFile(taskDefinition *ecs.TaskDefinition) (*ComposeFile, []string) {
+	var warnings composeWarnings
+
+	compose := &ComposeFile{
+		Services: make(map[string]*ComposeService),
+		Volumes:  make(map[string]*ComposeVolume),
+	}
+
+	networkMode := aws.StringValue(taskDefinition.NetworkMode)
+	task := "task"
+
+	// Host paths are bind mounts, other volumes are named ones
+	hostPaths := make(map[string]string)
+
+	for _, volume := range taskDefinition.Volumes {
+		name := aws.StringValue(volume.Name)
+
+		switch {
+		case volume.Host != nil && !strings.EqualFold("", aws.StringValue(volume.Host.SourcePath)):
+			hostPaths[name] = aws.StringValue(volume.Host.SourcePath)
+		case volume.DockerVolumeConfiguration != nil:
+			config := volume.DockerVolumeConfiguration
+			compose.Volumes[name] = &ComposeVolume{
+				Driver:     aws.StringValue(config.Driver),
+				DriverOpts: aws.StringValueMap(config.DriverOpts),
+				Labels:     aws.StringValueMap(config.Labels),
+			}
+		case volume.EfsVolumeConfiguration != nil:
+			warnings.add(task, "volume '%s' is an EFS file system, a local volume is used instead", name)
+			compose.Volumes[name] = &ComposeVolume{}
+		case volume.FsxWindowsFileServerVolumeConfiguration != nil:
+			warnings.add(task, "volume '%s' is a FSx file system, a local volume is used instead", name)
+			compose.Volumes[name] = &ComposeVolume{}
+		default:
+			compose.Volumes[name] = &ComposeVolume{}
+		}
+	}
+
+	if taskDefinition.TaskRoleArn != nil {
+		warnings.add(task, "taskRoleArn is not supported, containers need AWS credentials of your own")
+	}
+	if taskDefinition.Cpu != nil || taskDefinition.Memory != nil {
+		warnings.add(task, "cpu and memory of the task are not supported, only limits of each container are used")
+	}
+	if len(taskDefinition.PlacementConstraints) > 0 {
+		warnings.add(task, "placementConstraints are not supported")
+	}
+	if taskDefinition.ProxyConfiguration != nil {
+		warnings.add(task, "proxyConfiguration is not supported")
+	}
+	if aws.StringValue(taskDefinition.PidMode) == ecs.PidModeTask || aws.StringValue(taskDefinition.IpcMode) == ecs.IpcModeTask {
+		warnings.add(task, "pidMode and ipcMode 'task' are not supported")
+	}
+	if len(taskDefinition.InferenceAccelerators) > 0 {
+		warnings.add(task, "inferenceAccelerators are not supported")
+	}
+
+	// In awsvpc mode containers share the same network (localhost), as
+	// services sharing the network of the first one
+	var primary *ComposeService
+	var primaryName string
+
+	for _, def := range taskDefinition.ContainerDefinitions {
+		name := aws.StringValue(def.Name)
+
+		service := &ComposeService{
+			Image:           escapeCompose(aws.StringValue(def.Image)),
+			Entrypoint:      escapeComposeSlice(def.EntryPoint),
+			Command:         escapeComposeSlice(def.Command),
+			WorkingDir:      escapeCompose(aws.StringValue(def.WorkingDirectory)),
+			User:            escapeCompose(aws.StringValue(def.User)),
+			Hostname:        aws.StringValue(def.Hostname),
+			Environment:     escapeComposeMap(envvarsToMap(def.Environment)),
+			Links:           aws.StringValueSlice(def.Links),
+			CPUShares:       aws.Int64Value(def.Cpu),
+			MemLimit:        formatMiB(def.Memory),
+			MemReservation:  formatMiB(def.MemoryReservation),
+			Labels:          escapeComposeMap(aws.StringValueMap(def.DockerLabels)),
+			DNS:             aws.StringValueSlice(def.DnsServers),
+			DNSSearch:       aws.StringValueSlice(def.DnsSearchDomains),
+			SecurityOpt:     aws.StringValueSlice(def.DockerSecurityOptions),
+			Pid:             composePidMode(taskDefinition.PidMode),
+			Ipc:             composeIpcMode(taskDefinition.IpcMode),
+			Privileged:      aws.BoolValue(def.Privileged),
+			ReadOnly:        aws.BoolValue(def.ReadonlyRootFilesystem),
+			StdinOpen:       aws.BoolValue(def.Interactive),
+			Tty:             aws.BoolValue(def.PseudoTerminal),
+			StopGracePeriod: formatSeconds(def.StopTimeout),
+		}
+
+		// Values of secrets are read from your shell or .env file, so they're
+		// the only ones not escaped
+		for _, secret := range def.Secrets {
+			if service.Environment == nil {
+				service.Environment = make(ComposeEnvironment)
+			}
+
+			service.Environment[*secret.Name] = "${" + *secret.Name + "}"
+			warnings.add(name, "secret '%s' (from %s) is read from your shell or .env file", *secret.Name, GetSecretSource(*secret.ValueFrom))
+		}
+
+		switch networkMode {
+		case ecs.NetworkModeAwsvpc:
+			if primary != nil {
+				service.NetworkMode = "service:" + primaryName
+				primary.Ports = append(primary.Ports, composePorts(def, networkMode)...)
+				break
+			}
+
+			primary, primaryName = service, name
+			service.Ports = composePorts(def, networkMode)
+		case ecs.NetworkModeHost:
+			service.NetworkMode = "host"
+		case ecs.NetworkModeNone:
+			service.NetworkMode = "none"
+		default:
+			service.Ports = composePorts(def, networkMode)
+		}
+
+		if aws.BoolValue(def.DisableNetworking) {
+			service.NetworkMode = "none"
+		}
+
+		for _, dependency := range def.DependsOn {
+			if service.DependsOn == nil {
+				service.DependsOn = make(ComposeDependsOnMap)
+			}
+
+			condition := aws.StringValue(dependency.Condition)
+			if condition == ecs.ContainerConditionComplete {
+				warnings.add(name, "dependsOn '%s' with condition COMPLETE needs it to exit successfully", *dependency.ContainerName)
+			}
+
+			service.DependsOn[*dependency.ContainerName] = ComposeDependsOn{
+				Condition: composeConditions[condition],
+			}
+		}
+
+		for _, mountPoint := range def.MountPoints {
+			source := aws.StringValue(mountPoint.SourceVolume)
+			if hostPath, ok := hostPaths[source]; ok {
+				source = hostPath
+			}
+
+			volume := source + ":" + aws.StringValue(mountPoint.ContainerPath)
+			if aws.BoolValue(mountPoint.ReadOnly) {
+				volume += ":ro"
+			}
+
+			service.Volumes = append(service.Volumes, volume)
+		}
+
+		for _, volumeFrom := range def.VolumesFrom {
+			volume := aws.StringValue(volumeFrom.SourceContainer)
+			if aws.BoolValue(volumeFrom.ReadOnly) {
+				volume += ":ro"
+			}
+
+			service.VolumesFrom = append(service.VolumesFrom, volume)
+		}
+
+		if healthCheck := def.HealthCheck; healthCheck != nil {
+			service.Healthcheck = &ComposeHealthcheck{
+				Test:        escapeComposeSlice(healthCheck.Command),
+				Interval:    formatSeconds(healthCheck.Interval),
+				Timeout:     formatSeconds(healthCheck.Timeout),
+				Retries:     aws.Int64Value(healthCheck.Retries),
+				StartPeriod: formatSeconds(healthCheck.StartPeriod),
+			}
+		}
+
+		for _, ulimit := range def.Ulimits {
+			if service.Ulimits == nil {
+				service.Ulimits = make(map[string]ComposeUlimit)
+			}
+
+			service.Ulimits[*ulimit.Name] = ComposeUlimit{
+				Soft: aws.Int64Value(ulimit.SoftLimit),
+				Hard: aws.Int64Value(ulimit.HardLimit),
+			}
+		}
+
+		for _, systemControl := range def.SystemControls {
+			if service.Sysctls == nil {
+				service.Sysctls = make(ComposeMapping)
+			}
+
+			service.Sysctls[aws.StringValue(systemControl.Namespace)] = aws.StringValue(systemControl.Value)
+		}
+
+		for _, host := range def.ExtraHosts {
+			service.ExtraHosts = append(service.ExtraHosts, aws.StringValue(host.Hostname)+":"+aws.StringValue(host.IpAddress))
+		}
+
+		if linux := def.LinuxParameters; linux != nil {
+			service.Init = aws.BoolValue(linux.InitProcessEnabled)
+			service.ShmSize = formatMiB(linux.SharedMemorySize)
+
+			if linux.Capabilities != nil {
+				service.CapAdd = aws.StringValueSlice(linux.Capabilities.Add)
+				service.CapDrop = aws.StringValueSlice(linux.Capabilities.Drop)
+			}
+			for _, tmpfs := range linux.Tmpfs {
+				service.Tmpfs = append(service.Tmpfs, aws.StringValue(tmpfs.ContainerPath))
+			}
+			for _, device := range linux.Devices {
+				service.Devices = append(service.Devices, aws.StringValue(device.HostPath)+":"+aws.StringValue(device.ContainerPath))
+			}
+			if linux.MaxSwap != nil || linux.Swappiness != nil {
+				warnings.add(name, "linuxParameters maxSwap and swappiness are not supported")
+			}
+		}
+
+		if def.LogConfiguration != nil {
+			warnings.add(name, "logConfiguration (%s) is not supported, docker default logging is used", aws.StringValue(def.LogConfiguration.LogDriver))
+		}
+		if len(def.EnvironmentFiles) > 0 {
+			warnings.add(name, "environmentFiles (S3) are not supported")
+		}
+		if def.RepositoryCredentials != nil {
+			warnings.add(name, "repositoryCredentials are not supported, use docker login")
+		}
+		if def.FirelensConfiguration != nil {
+			warnings.add(name, "firelensConfiguration is not supported")
+		}
+		if len(def.ResourceRequirements) > 0 {
+			warnings.add(name, "resourceRequirements (GPU) are not supported")
+		}
+		if def.StartTimeout != nil {
+			warnings.add(name, "startTimeout is not supported")
+		}
+		if def.Essential != nil && !*def.Essential {
+			warnings.add(name, "essential is not supported, the container is started as the others")
+		}
+
+		compose.Services[name] = service
+	}
+
+	if len(compose.Volumes) == 0 {
+		compose.Volumes = nil
+	}
+
+	return compose, warnings
+}
+
+// composePidMode returns the pid of compose, only host has the same meaning.
+func composePidMode(pidMode *string) string {
+	if aws.StringValue(pidMode) == ecs.PidModeHost {
+		return "host"
+	}
+
+	return ""
+}
+
+func composeIpcMode(ipcMode *string) string {
+	switch aws.StringValue(ipcMode) {
+	case ecs.IpcModeHost:
+		return "host"
+	case ecs.IpcModeNone:
+		return "none"
+	}
+
+	return ""
+}
+
+func composePorts(def *ecs.ContainerDefinition, networkMode string) []string {
+	ports := make([]string, 0, len(def.PortMappings))
+	for _, portMapping := range def.PortMappings {
+		ports = append(ports, formatPortMapping(portMapping, networkMode))
+	}
+
+	return ports
+}
+
+// EncodeCompose returns taskDefinition as a docker-compose.yml, warnings are
+// added as comments at the top of it.
+func EncodeCompose(taskDefinition *ecs.TaskDefinition) ([]byte, []string) {
+	compose, warnings := NewComposeFile(taskDefinition)
+
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "# Generated from task definition '%s' revision[%d]\n", *taskDefinition.Family, *taskDefinition.Revision)
+	if len(warnings) > 0 {
+		sort.Strings(warnings)
+		buf.WriteString("#\n# Not supported by compose:\n#   - " + strings.Join(warnings, "\n#   - ") + "\n")
+	}
+
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(compose); err != nil {
+		fmt.Println("Cannot encode compose file:", err)
+		os.Exit(1)
+	}
+	encoder.Close()
+
+	return buf.Bytes(), warnings
+}
@@ -0,0 +1,1220 @@
+package aws
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Compose accepts the same attribute in more than one syntax, these decoders
+// keep only one of them.
+
+// UnmarshalYAML accepts a command as a list or as a string, split as a shell
+// does, e.g. npm run "my script".
+func (command *ComposeCommand) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		args, err := splitCommand(node.Value)
+		if err != nil {
+			return fmt.Errorf("line %d: %s", node.Line, err)
+		}
+
+		*command = args
+		return nil
+	}
+
+	var args []string
+	if err := node.Decode(&args); err != nil {
+		return err
+	}
+
+	*command = args
+	return nil
+}
+
+func splitCommand(command string) ([]string, error) {
+	var (
+		args    []string
+		arg     strings.Builder
+		inArg   bool
+		quote   rune
+		escaped bool
+	)
+
+	for _, r := range command {
+		switch {
+		case escaped:
+			arg.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inArg = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in '%s'", command)
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+
+	return args, nil
+}
+
+// decodeMapping reads a list of key=value or a map, keys without value are
+// given by unset.
+func decodeMapping(node *yaml.Node, unset func(key string) string) (map[string]string, error) {
+	mapping := make(map[string]string)
+
+	switch node.Kind {
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			parts := strings.SplitN(item.Value, "=", 2)
+			if len(parts) < 2 {
+				mapping[parts[0]] = unset(parts[0])
+				continue
+			}
+
+			mapping[parts[0]] = parts[1]
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i].Value, node.Content[i+1]
+			if value.Tag == "!!null" {
+				mapping[key] = unset(key)
+				continue
+			}
+
+			mapping[key] = value.Value
+		}
+	default:
+		return nil, fmt.Errorf("line %d: expected a list or a map", node.Line)
+	}
+
+	return mapping, nil
+}
+
+func (environment *ComposeEnvironment) UnmarshalYAML(node *yaml.Node) error {
+	mapping, err := decodeMapping(node, func(key string) string {
+		return "${" + key + "}"
+	})
+
+	*environment = mapping
+	return err
+}
+
+func (mapping *ComposeMapping) UnmarshalYAML(node *yaml.Node) error {
+	m, err := decodeMapping(node, func(string) string {
+		return ""
+	})
+
+	*mapping = m
+	return err
+}
+
+func (list *ComposeStringList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*list = ComposeStringList{node.Value}
+		return nil
+	}
+
+	var values []string
+	if err := node.Decode(&values); err != nil {
+		return err
+	}
+
+	*list = values
+	return nil
+}
+
+// UnmarshalYAML accepts a file, a list of files or a list of {path: file}.
+func (files *ComposeEnvFiles) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*files = ComposeEnvFiles{node.Value}
+		return nil
+	}
+
+	for _, item := range node.Content {
+		if item.Kind != yaml.MappingNode {
+			*files = append(*files, item.Value)
+			continue
+		}
+
+		var envFile struct {
+			Path string `yaml:"path"`
+		}
+		if err := item.Decode(&envFile); err != nil {
+			return err
+		}
+
+		*files = append(*files, envFile.Path)
+	}
+
+	return nil
+}
+
+// UnmarshalYAML converts the long syntax of ports to the short one.
+func (ports *ComposePorts) UnmarshalYAML(node *yaml.Node) error {
+	for _, item := range node.Content {
+		if item.Kind != yaml.MappingNode {
+			*ports = append(*ports, item.Value)
+			continue
+		}
+
+		var port struct {
+			Target    string `yaml:"target"`
+			Published string `yaml:"published"`
+			HostIP    string `yaml:"host_ip"`
+			Protocol  string `yaml:"protocol"`
+		}
+		if err := item.Decode(&port); err != nil {
+			return err
+		}
+
+		short := port.Target
+		if !strings.EqualFold("", port.Published) {
+			short = port.Published + ":" + short
+		}
+		if !strings.EqualFold("", port.HostIP) {
+			short = port.HostIP + ":" + short
+		}
+		if !strings.EqualFold("", port.Protocol) {
+			short += "/" + port.Protocol
+		}
+
+		*ports = append(*ports, short)
+	}
+
+	return nil
+}
+
+// UnmarshalYAML converts the long syntax of volumes to the short one.
+func (mounts *ComposeMounts) UnmarshalYAML(node *yaml.Node) error {
+	for _, item := range node.Content {
+		if item.Kind != yaml.MappingNode {
+			*mounts = append(*mounts, item.Value)
+			continue
+		}
+
+		var mount struct {
+			Source   string `yaml:"source"`
+			Target   string `yaml:"target"`
+			ReadOnly bool   `yaml:"read_only"`
+		}
+		if err := item.Decode(&mount); err != nil {
+			return err
+		}
+
+		short := mount.Target
+		if !strings.EqualFold("", mount.Source) {
+			short = mount.Source + ":" + short
+		}
+		if mount.ReadOnly {
+			short += ":ro"
+		}
+
+		*mounts = append(*mounts, short)
+	}
+
+	return nil
+}
+
+// UnmarshalYAML accepts a list of services, started before this one.
+func (dependsOn *ComposeDependsOnMap) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.SequenceNode {
+		*dependsOn = make(ComposeDependsOnMap)
+		for _, item := range node.Content {
+			(*dependsOn)[item.Value] = ComposeDependsOn{Condition: "service_started"}
+		}
+
+		return nil
+	}
+
+	var m map[string]ComposeDependsOn
+	if err := node.Decode(&m); err != nil {
+		return err
+	}
+
+	*dependsOn = m
+	return nil
+}
+
+// UnmarshalYAML accepts a single number as soft and hard limit.
+func (ulimit *ComposeUlimit) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		limit, err := strconv.ParseInt(node.Value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid ulimit '%s'", node.Line, node.Value)
+		}
+
+		ulimit.Soft, ulimit.Hard = limit, limit
+		return nil
+	}
+
+	type plain ComposeUlimit
+	return node.Decode((*plain)(ulimit))
+}
+
+func (test *ComposeHealthcheckTest) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*test = ComposeHealthcheckTest{"CMD-SHELL", node.Value}
+		return nil
+	}
+
+	var args []string
+	if err := node.Decode(&args); err != nil {
+		return err
+	}
+
+	*test = args
+	return nil
+}
+
+// ECS has no equivalent to these attributes of a service.
+var composeUnsupported = map[string]string{
+	"build":          "build is not supported, the image must be pushed to a registry",
+	"container_name": "container_name is ignored, the name of the service is used",
+	"expose":         "expose is not needed, containers of a task share the network",
+	"logging":        "logging is not supported, set logConfiguration (e.g. awslogs) afterwards",
+	"networks":       "networks are not supported, the network mode of the task is used",
+	"profiles":       "profiles are not supported, the service is always imported",
+	"restart":        "restart is not supported, ECS replaces stopped tasks of a service",
+	"secrets":        "secrets are not supported, add them as env vars from SSM or Secrets Manager",
+	"configs":        "configs are not supported",
+}
+
+// ECS dependency conditions of compose ones.
+var ecsConditions = map[string]string{
+	"service_started":                ecs.ContainerConditionStart,
+	"service_healthy":                ecs.ContainerConditionHealthy,
+	"service_completed_successfully": ecs.ContainerConditionSuccess,
+}
+
+var invalidVolumeNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// interpolateCompose replaces variables as compose does: $VAR, ${VAR},
+// ${VAR:-default}, ${VAR-default}, ${VAR:?error}, ${VAR?error}, ${VAR:+value}
+// and ${VAR+value}, $$ is a $. Unset variables are empty strings.
+func interpolateCompose(value string, lookup func(name string) (string, bool)) (string, error) {
+	var result strings.Builder
+
+	for i := 0; i < len(value); i++ {
+		if value[i] != '$' || i+1 == len(value) {
+			result.WriteByte(value[i])
+			continue
+		}
+
+		next := value[i+1]
+		switch {
+		case next == '$':
+			result.WriteByte('$')
+			i++
+		case next == '{':
+			end := matchingBrace(value, i+1)
+			if end < 0 {
+				return "", fmt.Errorf("unterminated variable in '%s'", value)
+			}
+
+			replacement, err := interpolateBraces(value[i+2:end], lookup)
+			if err != nil {
+				return "", err
+			}
+
+			result.WriteString(replacement)
+			i = end
+		case isVariableStart(next):
+			end := i + 1
+			for end < len(value) && isVariableChar(value[end]) {
+				end++
+			}
+
+			replacement, _ := lookup(value[i+1 : end])
+			result.WriteString(replacement)
+			i = end - 1
+		default:
+			result.WriteByte('$')
+		}
+	}
+
+	return result.String(), nil
+}
+
+// interpolateBraces resolves the content of ${...}.
+func interpolateBraces(expr string, lookup func(name string) (string, bool)) (string, error) {
+	end := 0
+	for end < len(expr) && isVariableChar(expr[end]) {
+		end++
+	}
+
+	name, op := expr[:end], expr[end:]
+	if strings.EqualFold("", name) {
+		return "", fmt.Errorf("invalid variable '${%s}'", expr)
+	}
+
+	value, ok := lookup(name)
+	if strings.EqualFold("", op) {
+		return value, nil
+	}
+
+	for _, operator := range []string{":-", ":?", ":+", "-", "?", "+"} {
+		if !strings.HasPrefix(op, operator) {
+			continue
+		}
+
+		arg, err := interpolateCompose(op[len(operator):], lookup)
+		if err != nil {
+			return "", err
+		}
+
+		// With : an empty value is the same as an unset one
+		isSet := ok && (!strings.HasPrefix(operator, ":") || !strings.EqualFold("", value))
+
+		switch strings.TrimPrefix(operator, ":") {
+		case "-":
+			if !isSet {
+				return arg, nil
+			}
+		case "?":
+			if !isSet {
+				return "", fmt.Errorf("variable '%s' is required: %s", name, arg)
+			}
+		case "+":
+			if isSet {
+				return arg, nil
+			}
+			return "", nil
+		}
+
+		return value, nil
+	}
+
+	return "", fmt.Errorf("invalid variable '${%s}'", expr)
+}
+
+func matchingBrace(value string, start int) int {
+	depth := 0
+	for i := start; i < len(value); i++ {
+		switch value[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+func isVariableStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isVariableChar(c byte) bool {
+	return isVariableStart(c) || (c >= '0' && c <= '9')
+}
+
+// newComposeLookup returns variables of the shell and then of the .env file
+// in dir, as compose does. unset returns the variables not found.
+func newComposeLookup(dir string) (lookup func(name string) (string, bool), unset func() []string) {
+	dotenv := make(map[string]string)
+
+	file, err := os.Open(filepath.Join(dir, ".env"))
+	if err == nil {
+		dotenv, err = ParseDotenv(file, false)
+		file.Close()
+
+		if err != nil {
+			fmt.Printf("Cannot parse '%s': %s\n", filepath.Join(dir, ".env"), err)
+			os.Exit(1)
+		}
+	}
+
+	missing := make(map[string]struct{})
+
+	lookup = func(name string) (string, bool) {
+		if value, ok := os.LookupEnv(name); ok {
+			return value, true
+		}
+		if value, ok := dotenv[name]; ok {
+			return value, true
+		}
+
+		missing[name] = struct{}{}
+		return "", false
+	}
+
+	unset = func() []string {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		return names
+	}
+
+	return lookup, unset
+}
+
+// interpolateComposeNode interpolates every value of node. Values of
+// environment are escaped again, so env vars without value (decoded as
+// "${KEY}") are told apart from the interpolated ones.
+func interpolateComposeNode(node *yaml.Node, lookup func(name string) (string, bool)) error {
+	return interpolateComposeValues(node, lookup, false)
+}
+
+func interpolateComposeValues(node *yaml.Node, lookup func(name string) (string, bool), inEnvironment bool) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			isEnvironment := inEnvironment || node.Content[i].Value == "environment"
+
+			if err := interpolateComposeValues(node.Content[i+1], lookup, isEnvironment); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		if !strings.Contains(node.Value, "$") {
+			return nil
+		}
+
+		value, err := interpolateCompose(node.Value, lookup)
+		if err != nil {
+			return fmt.Errorf("line %d: %s", node.Line, err)
+		}
+
+		if inEnvironment {
+			node.Value = escapeCompose(value)
+			return nil
+		}
+
+		node.Value = value
+		if node.Style == 0 {
+			// Resolved again, e.g. ${PORT} is a number
+			node.Tag = ""
+		}
+	default:
+		for _, child := range node.Content {
+			if err := interpolateComposeValues(child, lookup, inEnvironment); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseComposeDevice converts host[:container[:permissions]], permissions
+// are letters r, w and m.
+func parseComposeDevice(device string) *ecs.Device {
+	parts := strings.SplitN(device, ":", 3)
+
+	ecsDevice := &ecs.Device{
+		HostPath:      aws.String(parts[0]),
+		ContainerPath: aws.String(parts[0]),
+	}
+	if len(parts) > 1 {
+		ecsDevice.ContainerPath = aws.String(parts[1])
+	}
+	if len(parts) > 2 {
+		permissions := map[rune]string{
+			'r': ecs.DeviceCgroupPermissionRead,
+			'w': ecs.DeviceCgroupPermissionWrite,
+			'm': ecs.DeviceCgroupPermissionMknod,
+		}
+
+		for _, permission := range parts[2] {
+			if value, ok := permissions[permission]; ok {
+				ecsDevice.Permissions = append(ecsDevice.Permissions, aws.String(value))
+			}
+		}
+	}
+
+	return ecsDevice
+}
+
+// ReadComposeFile reads a docker-compose.yml, "-" reads from stdin. Variables
+// are interpolated from the shell and the .env file next to it.
+func ReadComposeFile(filename string) *ComposeFile {
+	var (
+		content []byte
+		err     error
+	)
+
+	if filename == "-" {
+		content, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		content, err = ioutil.ReadFile(filename)
+	}
+	if err != nil {
+		fmt.Printf("Cannot read '%s': %s\n", filename, err)
+		os.Exit(1)
+	}
+
+	var root yaml.Node
+
+	err = yaml.Unmarshal(content, &root)
+	if err != nil {
+		fmt.Printf("Cannot parse '%s' as compose file: %s\n", filename, err)
+		os.Exit(1)
+	}
+
+	lookup, unset := newComposeLookup(filepath.Dir(filename))
+
+	err = interpolateComposeNode(&root, lookup)
+	if err != nil {
+		fmt.Printf("Cannot interpolate '%s': %s\n", filename, err)
+		os.Exit(1)
+	}
+
+	var compose ComposeFile
+
+	err = root.Decode(&compose)
+	if err != nil {
+		fmt.Printf("Cannot parse '%s' as compose file: %s\n", filename, err)
+		os.Exit(1)
+	}
+
+	for _, name := range unset() {
+		compose.warnings.add("file", "variable '%s' is not set, an empty string is used", name)
+	}
+
+	if len(compose.Services) == 0 {
+		fmt.Printf("Compose file '%s' has no services\n", filename)
+		os.Exit(1)
+	}
+
+	return &compose
+}
+
+// parseComposeMiB converts a size as 512m or 1gb to MiB, without unit it's in
+// bytes.
+func parseComposeMiB(size string) (int64, error) {
+	size = strings.ToLower(strings.TrimSpace(size))
+	units := []struct {
+		suffix string
+		bytes  float64
+	}{
+		{"gb", 1 << 30}, {"g", 1 << 30},
+		{"mb", 1 << 20}, {"m", 1 << 20},
+		{"kb", 1 << 10}, {"k", 1 << 10},
+		{"b", 1},
+	}
+
+	multiplier := float64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(size, unit.suffix) {
+			size, multiplier = strings.TrimSuffix(size, unit.suffix), unit.bytes
+			break
+		}
+	}
+
+	value, err := strconv.ParseFloat(size, 64)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("invalid size '%s'", size)
+	}
+
+	return int64(math.Ceil(value * multiplier / (1 << 20))), nil
+}
+
+// parseComposeCPUs converts cpus as 0.5 to CPU units, 1 cpu is 1024 units.
+func parseComposeCPUs(cpus string) (int64, error) {
+	value, err := strconv.ParseFloat(strings.TrimSpace(cpus), 64)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("invalid cpus '%s'", cpus)
+	}
+
+	return int64(value * 1024), nil
+}
+
+func parseComposeSeconds(duration string) (int64, error) {
+	d, err := time.ParseDuration(duration)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration '%s'", duration)
+	}
+
+	return int64(math.Ceil(d.Seconds())), nil
+}
+
+// parseComposePort converts a port in the short syntax, [ip:][host:]container
+// with an optional /protocol.
+func parseComposePort(port, networkMode string) (*ecs.PortMapping, string) {
+	portMapping := &ecs.PortMapping{}
+
+	if pos := strings.LastIndex(port, "/"); pos > 0 {
+		portMapping.Protocol = aws.String(port[pos+1:])
+		port = port[:pos]
+	}
+
+	parts := strings.Split(port, ":")
+	if strings.Contains(port, "-") {
+		return nil, fmt.Sprintf("port range '%s' is not supported", port)
+	}
+
+	containerPort, err := strconv.ParseInt(parts[len(parts)-1], 10, 64)
+	if err != nil {
+		return nil, fmt.Sprintf("invalid port '%s'", port)
+	}
+	portMapping.ContainerPort = aws.Int64(containerPort)
+
+	var warning string
+
+	if len(parts) > 2 {
+		warning = fmt.Sprintf("host ip of port '%s' is not supported", port)
+	}
+	if len(parts) > 1 && !strings.EqualFold("", parts[len(parts)-2]) {
+		hostPort, err := strconv.ParseInt(parts[len(parts)-2], 10, 64)
+		if err != nil {
+			return nil, fmt.Sprintf("invalid port '%s'", port)
+		}
+
+		if networkMode == ecs.NetworkModeAwsvpc || networkMode == ecs.NetworkModeHost {
+			if hostPort != containerPort {
+				warning = fmt.Sprintf("port '%s' uses the container port %d, %s mode doesn't map ports", port, containerPort, networkMode)
+			}
+		} else {
+			portMapping.HostPort = aws.Int64(hostPort)
+		}
+	}
+
+	return portMapping, warning
+}
+
+// isHostPath returns true to bind mounts, e.g. /var/log or ./src.
+func isHostPath(source string) bool {
+	return strings.HasPrefix(source, "/") || strings.HasPrefix(source, ".") || strings.HasPrefix(source, "~")
+}
+
+// readComposeEnvFile reads a .env file relative to the compose file.
+func readComposeEnvFile(filename, dir string) (map[string]string, error) {
+	if !filepath.IsAbs(filename) {
+		filename = filepath.Join(dir, filename)
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ParseDotenv(file, false)
+}
+
+// NewComposeTaskDefinitionInput converts compose to a task definition of
+// family, env files are relative to dir. What has no equivalent in ECS is
+// returned as warnings.
+func NewComposeTaskDefinitionInput(compose *ComposeFile, family, networkMode, dir string) (*ecs.RegisterTaskDefinitionInput, []string) {
+	warnings := append(composeWarnings{}, compose.warnings...)
+
+	input := &ecs.RegisterTaskDefinitionInput{
+		Family:      aws.String(family),
+		NetworkMode: aws.String(networkMode),
+	}
+
+	task := "task"
+	for key := range compose.Extra {
+		if key == "version" || key == "name" || strings.HasPrefix(key, "x-") {
+			continue
+		}
+
+		warnings.add(task, "top-level %s are not supported", key)
+	}
+
+	names := make([]string, 0, len(compose.Volumes))
+	for name := range compose.Volumes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	volumes := make(map[string]bool)
+	for _, name := range names {
+		volume := &ecs.Volume{Name: aws.String(name)}
+
+		if composeVolume := compose.Volumes[name]; composeVolume != nil {
+			if composeVolume.External {
+				warnings.add(task, "volume '%s' is external, a volume of the task is used instead", name)
+			}
+
+			if !strings.EqualFold("", composeVolume.Driver) || len(composeVolume.DriverOpts) > 0 || len(composeVolume.Labels) > 0 {
+				volume.DockerVolumeConfiguration = &ecs.DockerVolumeConfiguration{
+					Driver:     stringOrNil(composeVolume.Driver),
+					DriverOpts: aws.StringMap(composeVolume.DriverOpts),
+					Labels:     aws.StringMap(composeVolume.Labels),
+				}
+			}
+		}
+
+		input.Volumes = append(input.Volumes, volume)
+		volumes[name] = true
+	}
+
+	// Volumes used by a service without declaring them are volumes of the task
+	addVolume := func(name string, host *ecs.HostVolumeProperties) {
+		if volumes[name] {
+			return
+		}
+
+		input.Volumes = append(input.Volumes, &ecs.Volume{Name: aws.String(name), Host: host})
+		volumes[name] = true
+	}
+
+	services := make([]string, 0, len(compose.Services))
+	for name := range compose.Services {
+		services = append(services, name)
+	}
+	sort.Strings(services)
+
+	// Services others wait to complete are not essential, e.g. migrations
+	notEssential := make(map[string]bool)
+	for _, service := range compose.Services {
+		for name, dependency := range service.DependsOn {
+			if dependency.Condition == "service_completed_successfully" {
+				notEssential[name] = true
+			}
+		}
+	}
+
+	if networkMode == ecs.NetworkModeAwsvpc && len(services) > 1 {
+		warnings.add(task, "containers share the network in awsvpc mode, use localhost instead of the name of other services")
+	}
+
+	for _, name := range services {
+		service := compose.Services[name]
+
+		if strings.EqualFold("", service.Image) {
+			fmt.Printf("Service '%s' needs an image, build is not supported\n", name)
+			os.Exit(1)
+		}
+
+		def := &ecs.ContainerDefinition{
+			Name:                   aws.String(name),
+			Image:                  aws.String(service.Image),
+			EntryPoint:             aws.StringSlice(service.Entrypoint),
+			Command:                aws.StringSlice(service.Command),
+			WorkingDirectory:       stringOrNil(service.WorkingDir),
+			User:                   stringOrNil(service.User),
+			DockerLabels:           aws.StringMap(service.Labels),
+			DnsServers:             aws.StringSlice(service.DNS),
+			DnsSearchDomains:       aws.StringSlice(service.DNSSearch),
+			DockerSecurityOptions:  aws.StringSlice(service.SecurityOpt),
+			Privileged:             trueOrNil(service.Privileged),
+			ReadonlyRootFilesystem: trueOrNil(service.ReadOnly),
+			Interactive:            trueOrNil(service.StdinOpen),
+			PseudoTerminal:         trueOrNil(service.Tty),
+		}
+
+		if notEssential[name] {
+			def.Essential = aws.Bool(false)
+		}
+
+		if !strings.EqualFold("", service.Hostname) {
+			if networkMode == ecs.NetworkModeAwsvpc {
+				warnings.add(name, "hostname is not supported in awsvpc mode")
+			} else {
+				def.Hostname = aws.String(service.Hostname)
+			}
+		}
+
+		if len(service.Links) > 0 {
+			if networkMode == ecs.NetworkModeBridge {
+				def.Links = aws.StringSlice(service.Links)
+			} else {
+				warnings.add(name, "links are only supported in bridge mode")
+			}
+		}
+
+		switch {
+		case strings.EqualFold("", service.NetworkMode):
+		case strings.HasPrefix(service.NetworkMode, "service:") && networkMode == ecs.NetworkModeAwsvpc:
+			// Containers of the task already share the network
+		case service.NetworkMode != networkMode:
+			warnings.add(name, "network_mode '%s' is not supported, the task uses %s mode", service.NetworkMode, networkMode)
+		}
+
+		// Env files first, environment has precedence over them
+		envvars := make(map[string]string)
+		for _, envFile := range service.EnvFile {
+			values, err := readComposeEnvFile(envFile, dir)
+			if err != nil {
+				fmt.Printf("Cannot read env file '%s' of '%s': %s\n", envFile, name, err)
+				os.Exit(1)
+			}
+
+			for key, value := range values {
+				envvars[key] = value
+			}
+		}
+		for key, value := range service.Environment {
+			// Values were escaped by interpolateComposeNode, only env vars
+			// without value have a single $
+			if value == "${"+key+"}" {
+				warnings.add(name, "env var '%s' has no value, it's read from your shell, add it with 'env' command or as a secret", key)
+				delete(envvars, key)
+				continue
+			}
+
+			envvars[key] = strings.Replace(value, "$$", "$", -1)
+		}
+
+		keys := make([]string, 0, len(envvars))
+		for key := range envvars {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			def.Environment = append(def.Environment, &ecs.KeyValuePair{
+				Name:  aws.String(key),
+				Value: aws.String(envvars[key]),
+			})
+		}
+
+		for _, port := range service.Ports {
+			portMapping, warning := parseComposePort(port, networkMode)
+			if !strings.EqualFold("", warning) {
+				warnings.add(name, "%s", warning)
+			}
+			if portMapping != nil {
+				def.PortMappings = append(def.PortMappings, portMapping)
+			}
+		}
+
+		// Resources, deploy.resources has precedence as in docker compose
+		memory, memoryReservation, cpus := service.MemLimit, service.MemReservation, service.CPUs
+		if service.Deploy != nil {
+			resources := service.Deploy.Resources
+			if !strings.EqualFold("", resources.Limits.Memory) {
+				memory = resources.Limits.Memory
+			}
+			if !strings.EqualFold("", resources.Reservations.Memory) {
+				memoryReservation = resources.Reservations.Memory
+			}
+			if !strings.EqualFold("", resources.Limits.CPUs) {
+				cpus = resources.Limits.CPUs
+			}
+
+			for key := range service.Deploy.Extra {
+				warnings.add(name, "deploy.%s is not supported, it's configured in the ECS service", key)
+			}
+		}
+
+		if !strings.EqualFold("", memory) {
+			mib, err := parseComposeMiB(memory)
+			if err != nil {
+				warnings.add(name, "memory limit: %s", err)
+			} else {
+				def.Memory = aws.Int64(mib)
+			}
+		}
+		if !strings.EqualFold("", memoryReservation) {
+			mib, err := parseComposeMiB(memoryReservation)
+			if err != nil {
+				warnings.add(name, "memory reservation: %s", err)
+			} else {
+				def.MemoryReservation = aws.Int64(mib)
+			}
+		}
+		if def.Memory == nil && def.MemoryReservation == nil {
+			warnings.add(name, "no memory limit, set memory of the container or of the task before using EC2")
+		}
+
+		switch {
+		case !strings.EqualFold("", cpus):
+			units, err := parseComposeCPUs(cpus)
+			if err != nil {
+				warnings.add(name, "%s", err)
+			} else {
+				def.Cpu = aws.Int64(units)
+			}
+		case service.CPUShares > 0:
+			def.Cpu = aws.Int64(service.CPUShares)
+		}
+
+		if !strings.EqualFold("", service.StopGracePeriod) {
+			seconds, err := parseComposeSeconds(service.StopGracePeriod)
+			if err != nil {
+				warnings.add(name, "stop_grace_period: %s", err)
+			} else {
+				def.StopTimeout = aws.Int64(seconds)
+			}
+		}
+
+		dependencies := make([]string, 0, len(service.DependsOn))
+		for dependency := range service.DependsOn {
+			dependencies = append(dependencies, dependency)
+		}
+		sort.Strings(dependencies)
+
+		for _, dependency := range dependencies {
+			condition, ok := ecsConditions[service.DependsOn[dependency].Condition]
+			if !ok {
+				condition = ecs.ContainerConditionStart
+			}
+
+			def.DependsOn = append(def.DependsOn, &ecs.ContainerDependency{
+				ContainerName: aws.String(dependency),
+				Condition:     aws.String(condition),
+			})
+		}
+
+		for _, mount := range service.Volumes {
+			parts := strings.Split(mount, ":")
+
+			var source, target string
+			readOnly := len(parts) > 2 && parts[2] == "ro"
+
+			switch len(parts) {
+			case 1:
+				// Anonymous volume, only used by this container
+				source = invalidVolumeNameChars.ReplaceAllString(name+parts[0], "-")
+				target = parts[0]
+			default:
+				source, target = parts[0], parts[1]
+			}
+
+			switch {
+			case filepath.IsAbs(source):
+				hostPath := source
+				source = strings.Trim(invalidVolumeNameChars.ReplaceAllString(hostPath, "-"), "-")
+				addVolume(source, &ecs.HostVolumeProperties{SourcePath: aws.String(hostPath)})
+			case isHostPath(source):
+				warnings.add(name, "relative bind mount '%s' is not supported, the image must have these files", mount)
+				continue
+			default:
+				addVolume(source, nil)
+			}
+
+			def.MountPoints = append(def.MountPoints, &ecs.MountPoint{
+				SourceVolume:  aws.String(source),
+				ContainerPath: aws.String(target),
+				ReadOnly:      trueOrNil(readOnly),
+			})
+		}
+
+		for _, volumeFrom := range service.VolumesFrom {
+			parts := strings.Split(volumeFrom, ":")
+			def.VolumesFrom = append(def.VolumesFrom, &ecs.VolumeFrom{
+				SourceContainer: aws.String(strings.TrimPrefix(parts[0], "container:")),
+				ReadOnly:        trueOrNil(len(parts) > 1 && parts[1] == "ro"),
+			})
+		}
+
+		if healthcheck := service.Healthcheck; healthcheck != nil && !healthcheck.Disable && len(healthcheck.Test) > 0 && healthcheck.Test[0] != "NONE" {
+			def.HealthCheck = &ecs.HealthCheck{
+				Command: aws.StringSlice(healthcheck.Test),
+			}
+			if healthcheck.Retries > 0 {
+				def.HealthCheck.Retries = aws.Int64(healthcheck.Retries)
+			}
+
+			durations := []struct {
+				field string
+				value string
+				to    **int64
+			}{
+				{"interval", healthcheck.Interval, &def.HealthCheck.Interval},
+				{"timeout", healthcheck.Timeout, &def.HealthCheck.Timeout},
+				{"start_period", healthcheck.StartPeriod, &def.HealthCheck.StartPeriod},
+			}
+			for _, duration := range durations {
+				if strings.EqualFold("", duration.value) {
+					continue
+				}
+
+				seconds, err := parseComposeSeconds(duration.value)
+				if err != nil {
+					warnings.add(name, "healthcheck %s: %s", duration.field, err)
+					continue
+				}
+
+				*duration.to = aws.Int64(seconds)
+			}
+		}
+
+		ulimits := make([]string, 0, len(service.Ulimits))
+		for ulimit := range service.Ulimits {
+			ulimits = append(ulimits, ulimit)
+		}
+		sort.Strings(ulimits)
+
+		for _, ulimit := range ulimits {
+			def.Ulimits = append(def.Ulimits, &ecs.Ulimit{
+				Name:      aws.String(ulimit),
+				SoftLimit: aws.Int64(service.Ulimits[ulimit].Soft),
+				HardLimit: aws.Int64(service.Ulimits[ulimit].Hard),
+			})
+		}
+
+		sysctls := make([]string, 0, len(service.Sysctls))
+		for sysctl := range service.Sysctls {
+			sysctls = append(sysctls, sysctl)
+		}
+		sort.Strings(sysctls)
+
+		for _, sysctl := range sysctls {
+			def.SystemControls = append(def.SystemControls, &ecs.SystemControl{
+				Namespace: aws.String(sysctl),
+				Value:     aws.String(service.Sysctls[sysctl]),
+			})
+		}
+
+		for _, host := range service.ExtraHosts {
+			parts := strings.SplitN(strings.Replace(host, "=", ":", 1), ":", 2)
+			if len(parts) < 2 {
+				warnings.add(name, "invalid extra host '%s'", host)
+				continue
+			}
+
+			def.ExtraHosts = append(def.ExtraHosts, &ecs.HostEntry{
+				Hostname:  aws.String(parts[0]),
+				IpAddress: aws.String(parts[1]),
+			})
+		}
+
+		linux := &ecs.LinuxParameters{
+			InitProcessEnabled: trueOrNil(service.Init),
+		}
+		if len(service.CapAdd) > 0 || len(service.CapDrop) > 0 {
+			linux.Capabilities = &ecs.KernelCapabilities{
+				Add:  aws.StringSlice(service.CapAdd),
+				Drop: aws.StringSlice(service.CapDrop),
+			}
+		}
+		if !strings.EqualFold("", service.ShmSize) {
+			mib, err := parseComposeMiB(service.ShmSize)
+			if err != nil {
+				warnings.add(name, "shm_size: %s", err)
+			} else {
+				linux.SharedMemorySize = aws.Int64(mib)
+			}
+		}
+		for _, device := range service.Devices {
+			linux.Devices = append(linux.Devices, parseComposeDevice(device))
+		}
+		if len(service.Tmpfs) > 0 {
+			warnings.add(name, "tmpfs is not supported without a size, add linuxParameters.tmpfs afterwards")
+		}
+		if linux.InitProcessEnabled != nil || linux.Capabilities != nil || linux.SharedMemorySize != nil || len(linux.Devices) > 0 {
+			def.LinuxParameters = linux
+		}
+
+		if service.Pid == "host" {
+			input.PidMode = aws.String(ecs.PidModeHost)
+		}
+		if service.Ipc == "host" {
+			input.IpcMode = aws.String(ecs.IpcModeHost)
+		}
+
+		extra := make([]string, 0, len(service.Extra))
+		for key := range service.Extra {
+			if !strings.HasPrefix(key, "x-") {
+				extra = append(extra, key)
+			}
+		}
+		sort.Strings(extra)
+
+		for _, key := range extra {
+			if message, ok := composeUnsupported[key]; ok {
+				warnings.add(name, "%s", message)
+				continue
+			}
+
+			warnings.add(name, "%s is not supported", key)
+		}
+
+		input.ContainerDefinitions = append(input.ContainerDefinitions, def)
+	}
+
+	sort.Strings(warnings)
+	return input, warnings
+}
+
+func stringOrNil(value string) *string {
+	if strings.EqualFold("", value) {
+		return nil
+	}
+
+	return aws.String(value)
+}
+
+func trueOrNil(value bool) *bool {
+	if !value {
+		return nil
+	}
+
+	return aws.Bool(true)
+}
+
+// PreviewImport shows the task definition imported from a compose file, or
+// what changes when its family already exists, and what was not imported. It
+// returns false when there is nothing to register.
+func (sess *AWSSession) PreviewImport(input *ecs.RegisterTaskDefinitionInput, warnings []string, reveal bool) bool {
+	masked := maskTaskDefinitionInput(input, sess.masker(reveal))
+
+	current := describeTaskDefinitionIfExists(sess.Client, *input.Family)
+	if current == nil {
+		fmt.Printf("New task definition '%s':\n", *input.Family)
+		os.Stdout.Write(EncodeTaskDefinition(masked, FormatYAML))
+	} else {
+		currentInput := NewRegisterTaskDefinitionInput(current)
+
+		// Changes are found on real values, masked ones are only shown
+		diff := DiffTaskDefinitionInputs(currentInput, input)
+		if !diff.HasChanges() {
+			fmt.Printf("Nothing to update, task definition '%s' is equal to revision[%d]\n", *input.Family, *current.Revision)
+			return false
+		}
+
+		maskedDiff := DiffTaskDefinitionInputs(maskTaskDefinitionInput(currentInput, sess.masker(reveal)), masked)
+
+		fmt.Printf("Changes to task definition '%s' revision[%d]:\n", *input.Family, *current.Revision)
+		if maskedDiff.HasChanges() {
+			maskedDiff.Print(os.Stdout)
+		} else {
+			fmt.Println("  only sensitive values changed, use --reveal to see them")
+		}
+	}
+
+	if len(warnings) > 0 {
+		fmt.Printf("\nNot supported by ECS:\n  - %s\n", strings.Join(warnings, "\n  - "))
+	}
+
+	return true
+}
+
+// RegisterTaskDefinition registers input and returns the ARN of the new
+// revision.
+func (sess *AWSSession) RegisterTaskDefinition(input *ecs.RegisterTaskDefinitionInput) string {
+	taskDefinition := RegisterTaskDefinitionInput(sess.Client, input)
+	return *taskDefinition.TaskDefinitionArn
+}
@@ -0,0 +1,402 @@
+package aws
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	yaml "gopkg.in/yaml.v3"
+)
+
+func TestComposeServiceSyntaxes(t *testing.T) {
+	tests := []struct {
+		name    string
+		short   string
+		long    string
+		compare func(service ComposeService) interface{}
+		want    interface{}
+	}{
+		{
+			name:    "command",
+			short:   `command: npm run "my script" --flag='a b'`,
+			long:    `command: ["npm", "run", "my script", "--flag=a b"]`,
+			compare: func(service ComposeService) interface{} { return []string(service.Command) },
+			want:    []string{"npm", "run", "my script", "--flag=a b"},
+		},
+		{
+			name:    "environment",
+			short:   "environment: [A=1, B=, C]",
+			long:    "environment: {A: 1, B: '', C: null}",
+			compare: func(service ComposeService) interface{} { return map[string]string(service.Environment) },
+			want:    map[string]string{"A": "1", "B": "", "C": "${C}"},
+		},
+		{
+			name:    "labels",
+			short:   "labels: [com.example.team=web, empty]",
+			long:    "labels: {com.example.team: web, empty: null}",
+			compare: func(service ComposeService) interface{} { return map[string]string(service.Labels) },
+			want:    map[string]string{"com.example.team": "web", "empty": ""},
+		},
+		{
+			name:  "ports",
+			short: `ports: ["127.0.0.1:8080:80/udp", "9000"]`,
+			long: "ports:\n" +
+				"  - {target: 80, published: 8080, host_ip: 127.0.0.1, protocol: udp}\n" +
+				"  - target: 9000\n",
+			compare: func(service ComposeService) interface{} { return []string(service.Ports) },
+			want:    []string{"127.0.0.1:8080:80/udp", "9000"},
+		},
+		{
+			name:  "volumes",
+			short: `volumes: ["data:/data:ro", "/tmp"]`,
+			long: "volumes:\n" +
+				"  - {type: volume, source: data, target: /data, read_only: true}\n" +
+				"  - {type: tmpfs, target: /tmp}\n",
+			compare: func(service ComposeService) interface{} { return []string(service.Volumes) },
+			want:    []string{"data:/data:ro", "/tmp"},
+		},
+		{
+			name:    "depends_on",
+			short:   "depends_on: [db]",
+			long:    "depends_on: {db: {condition: service_started}}",
+			compare: func(service ComposeService) interface{} { return map[string]ComposeDependsOn(service.DependsOn) },
+			want:    map[string]ComposeDependsOn{"db": {Condition: "service_started"}},
+		},
+		{
+			name:    "env_file",
+			short:   "env_file: .env",
+			long:    "env_file: [{path: .env}]",
+			compare: func(service ComposeService) interface{} { return []string(service.EnvFile) },
+			want:    []string{".env"},
+		},
+		{
+			name:    "tmpfs",
+			short:   "tmpfs: /run",
+			long:    "tmpfs: [/run]",
+			compare: func(service ComposeService) interface{} { return []string(service.Tmpfs) },
+			want:    []string{"/run"},
+		},
+		{
+			name:    "ulimits",
+			short:   "ulimits: {nofile: 1024}",
+			long:    "ulimits: {nofile: {soft: 1024, hard: 1024}}",
+			compare: func(service ComposeService) interface{} { return service.Ulimits },
+			want:    map[string]ComposeUlimit{"nofile": {Soft: 1024, Hard: 1024}},
+		},
+		{
+			name:    "healthcheck",
+			short:   "healthcheck: {test: curl -f http://localhost}",
+			long:    `healthcheck: {test: ["CMD-SHELL", "curl -f http://localhost"]}`,
+			compare: func(service ComposeService) interface{} { return []string(service.Healthcheck.Test) },
+			want:    []string{"CMD-SHELL", "curl -f http://localhost"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, content := range []string{test.short, test.long} {
+				var service ComposeService
+				if err := yaml.Unmarshal([]byte(content), &service); err != nil {
+					t.Fatalf("%s: unexpected error: %s", content, err)
+				}
+
+				if got := test.compare(service); !reflect.DeepEqual(got, test.want) {
+					t.Errorf("%s: got %v, want %v", content, got, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+		err     bool
+	}{
+		{command: "npm start", want: []string{"npm", "start"}},
+		{command: "  sh  -c   'echo $HOME'  ", want: []string{"sh", "-c", "echo $HOME"}},
+		{command: `echo "a \"b\"" c\ d`, want: []string{"echo", `a "b"`, "c d"}},
+		{command: `echo '' ""`, want: []string{"echo", "", ""}},
+		{command: `echo 'a\b'`, want: []string{"echo", `a\b`}},
+		{command: `echo "open`, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.command, func(t *testing.T) {
+			got, err := splitCommand(test.command)
+			if (err != nil) != test.err {
+				t.Fatalf("got error %v, want error %v", err, test.err)
+			}
+			if !test.err && !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestInterpolateCompose(t *testing.T) {
+	variables := map[string]string{
+		"TAG":   "1.2",
+		"EMPTY": "",
+		"PORT":  "8080",
+	}
+	lookup := func(name string) (string, bool) {
+		value, ok := variables[name]
+		return value, ok
+	}
+
+	tests := []struct {
+		value string
+		want  string
+		err   string
+	}{
+		{value: "nginx:$TAG", want: "nginx:1.2"},
+		{value: "nginx:${TAG}-alpine", want: "nginx:1.2-alpine"},
+		{value: "$$TAG costs $$5", want: "$TAG costs $5"},
+		{value: "price: 5$", want: "price: 5$"},
+		{value: "${MISSING}", want: ""},
+		{value: "${MISSING:-default}", want: "default"},
+		{value: "${EMPTY:-default}", want: "default"},
+		{value: "${EMPTY-default}", want: ""},
+		{value: "${MISSING-default}", want: "default"},
+		{value: "${TAG:+set}", want: "set"},
+		{value: "${EMPTY:+set}", want: ""},
+		{value: "${EMPTY+set}", want: "set"},
+		{value: "${MISSING+set}", want: ""},
+		{value: "${MISSING:-${PORT}}", want: "8080"},
+		{value: "${MISSING:-${OTHER:-${TAG}}}", want: "1.2"},
+		{value: "${TAG:?tag is required}", want: "1.2"},
+		{value: "${MISSING:?tag is required}", err: "tag is required"},
+		{value: "${EMPTY:?is empty}", err: "is empty"},
+		{value: "${EMPTY?is unset}", want: ""},
+		{value: "${TAG", err: "unterminated"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			got, err := interpolateCompose(test.value, lookup)
+			if !strings.EqualFold("", test.err) {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("got %v, want error containing %q", err, test.err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestInterpolateComposeNode(t *testing.T) {
+	content := "services:\n" +
+		"  web:\n" +
+		"    image: nginx:${TAG}\n" +
+		"    cpu_shares: ${SHARES}\n" +
+		"    user: '${SHARES}'\n" +
+		"    environment:\n" +
+		"      LOG_LEVEL: ${LOG_LEVEL:-info}\n" +
+		"      PRICE: $$5\n" +
+		"      DOLLAR: ${DOLLAR}\n" +
+		"      PASSWORD:\n" +
+		"  worker:\n" +
+		"    image: worker\n" +
+		"    environment:\n" +
+		"      - TAG=v${TAG}\n" +
+		"      - PASSWORD\n"
+
+	variables := map[string]string{"TAG": "1.2", "SHARES": "512", "DOLLAR": "a$b"}
+	lookup := func(name string) (string, bool) {
+		value, ok := variables[name]
+		return value, ok
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &root); err != nil {
+		t.Fatal(err)
+	}
+	if err := interpolateComposeNode(&root, lookup); err != nil {
+		t.Fatal(err)
+	}
+
+	var compose ComposeFile
+	if err := root.Decode(&compose); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	web := compose.Services["web"]
+	if web.Image != "nginx:1.2" {
+		t.Errorf("got image %q", web.Image)
+	}
+	if web.CPUShares != 512 {
+		t.Errorf("got cpu_shares %d", web.CPUShares)
+	}
+	if web.User != "512" {
+		t.Errorf("got user %q", web.User)
+	}
+
+	// Interpolated values are escaped again, env vars without value are not
+	wantEnvironment := map[string]ComposeEnvironment{
+		"web":    {"LOG_LEVEL": "info", "PRICE": "$$5", "DOLLAR": "a$$b", "PASSWORD": "${PASSWORD}"},
+		"worker": {"TAG": "v1.2", "PASSWORD": "${PASSWORD}"},
+	}
+	for name, want := range wantEnvironment {
+		if got := compose.Services[name].Environment; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got environment %q, want %q", name, got, want)
+		}
+	}
+
+	input, warnings := NewComposeTaskDefinitionInput(&compose, "app", ecs.NetworkModeBridge, ".")
+
+	envvars := make(map[string]string)
+	for _, envvar := range input.ContainerDefinitions[0].Environment {
+		envvars[*envvar.Name] = *envvar.Value
+	}
+	if want := map[string]string{"LOG_LEVEL": "info", "PRICE": "$5", "DOLLAR": "a$b"}; !reflect.DeepEqual(envvars, want) {
+		t.Errorf("got env vars %q, want %q", envvars, want)
+	}
+
+	var passwordWarnings int
+	for _, warning := range warnings {
+		if strings.Contains(warning, "env var 'PASSWORD' has no value") {
+			passwordWarnings++
+		}
+	}
+	if passwordWarnings != 2 {
+		t.Errorf("got %d warnings of PASSWORD, want 2: %q", passwordWarnings, warnings)
+	}
+}
+
+func TestParseComposePort(t *testing.T) {
+	tests := []struct {
+		port        string
+		networkMode string
+		want        *ecs.PortMapping
+		warning     string
+	}{
+		{port: "80", networkMode: ecs.NetworkModeAwsvpc, want: &ecs.PortMapping{ContainerPort: aws.Int64(80)}},
+		{port: "80:80", networkMode: ecs.NetworkModeAwsvpc, want: &ecs.PortMapping{ContainerPort: aws.Int64(80)}},
+		{port: "8080:80", networkMode: ecs.NetworkModeAwsvpc, want: &ecs.PortMapping{ContainerPort: aws.Int64(80)}, warning: "uses the container port 80"},
+		{port: "8080:80", networkMode: ecs.NetworkModeBridge, want: &ecs.PortMapping{ContainerPort: aws.Int64(80), HostPort: aws.Int64(8080)}},
+		{port: "53/udp", networkMode: ecs.NetworkModeBridge, want: &ecs.PortMapping{ContainerPort: aws.Int64(53), Protocol: aws.String("udp")}},
+		{port: "127.0.0.1:8080:80", networkMode: ecs.NetworkModeBridge, want: &ecs.PortMapping{ContainerPort: aws.Int64(80), HostPort: aws.Int64(8080)}, warning: "host ip"},
+		{port: "127.0.0.1::80", networkMode: ecs.NetworkModeBridge, want: &ecs.PortMapping{ContainerPort: aws.Int64(80)}, warning: "host ip"},
+		{port: "3000-3005", networkMode: ecs.NetworkModeBridge, warning: "port range"},
+		{port: "http", networkMode: ecs.NetworkModeBridge, warning: "invalid port"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.networkMode+" "+test.port, func(t *testing.T) {
+			got, warning := parseComposePort(test.port, test.networkMode)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+			if strings.EqualFold("", test.warning) != strings.EqualFold("", warning) || !strings.Contains(warning, test.warning) {
+				t.Errorf("got warning %q, want %q", warning, test.warning)
+			}
+		})
+	}
+}
+
+func TestParseComposeMiB(t *testing.T) {
+	tests := []struct {
+		size string
+		want int64
+		err  bool
+	}{
+		{size: "512m", want: 512},
+		{size: "512MB", want: 512},
+		{size: "1g", want: 1024},
+		{size: "1.5gb", want: 1536},
+		{size: "2048k", want: 2},
+		{size: "1048576", want: 1},
+		{size: "1000000b", want: 1},
+		{size: "0", err: true},
+		{size: "lots", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.size, func(t *testing.T) {
+			got, err := parseComposeMiB(test.size)
+			if (err != nil) != test.err {
+				t.Fatalf("got error %v, want error %v", err, test.err)
+			}
+			if got != test.want {
+				t.Errorf("got %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseComposeCPUs(t *testing.T) {
+	tests := []struct {
+		cpus string
+		want int64
+		err  bool
+	}{
+		{cpus: "1", want: 1024},
+		{cpus: "0.5", want: 512},
+		{cpus: " 2.25 ", want: 2304},
+		{cpus: "0", err: true},
+		{cpus: "half", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.cpus, func(t *testing.T) {
+			got, err := parseComposeCPUs(test.cpus)
+			if (err != nil) != test.err {
+				t.Fatalf("got error %v, want error %v", err, test.err)
+			}
+			if got != test.want {
+				t.Errorf("got %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseComposeDevice(t *testing.T) {
+	tests := []struct {
+		device string
+		want   *ecs.Device
+	}{
+		{
+			device: "/dev/fuse",
+			want:   &ecs.Device{HostPath: aws.String("/dev/fuse"), ContainerPath: aws.String("/dev/fuse")},
+		},
+		{
+			device: "/dev/sda:/dev/xvda",
+			want:   &ecs.Device{HostPath: aws.String("/dev/sda"), ContainerPath: aws.String("/dev/xvda")},
+		},
+		{
+			device: "/dev/sda:/dev/xvda:rwm",
+			want: &ecs.Device{
+				HostPath:      aws.String("/dev/sda"),
+				ContainerPath: aws.String("/dev/xvda"),
+				Permissions:   aws.StringSlice([]string{ecs.DeviceCgroupPermissionRead, ecs.DeviceCgroupPermissionWrite, ecs.DeviceCgroupPermissionMknod}),
+			},
+		},
+		{
+			device: "/dev/sda:/dev/xvda:r",
+			want: &ecs.Device{
+				HostPath:      aws.String("/dev/sda"),
+				ContainerPath: aws.String("/dev/xvda"),
+				Permissions:   aws.StringSlice([]string{ecs.DeviceCgroupPermissionRead}),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.device, func(t *testing.T) {
+			if got := parseComposeDevice(test.device); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
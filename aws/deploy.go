@@ -62,6 +62,8 @@ func (sess *AWSSession) Deploy(service, taskDefinition string) {
 
 	_, err := svc.UpdateService(params)
 	checkErr("UpdateService", err)
+
+	sess.recordDeploy(taskDefinition)
 }
 
 func (sess *AWSSession) Rollback(service string) {
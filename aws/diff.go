@@ -0,0 +1,108 @@
+package aws
+
+import (
+	"fmt"
+	"io"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+type (
+	DiffLine struct {
+		Op   byte
+		Text string
+	}
+
+	// LineDiff is the result of DiffLines, Op is ' ' for unchanged lines, '-'
+	// for removed and '+' for added ones.
+	LineDiff []DiffLine
+)
+
+// DiffLines compares a and b using the longest common subsequence.
+func DiffLines(a, b []string) LineDiff {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	diff := make(LineDiff, 0, len(a)+len(b))
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			diff = append(diff, DiffLine{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, DiffLine{'-', a[i]})
+			i++
+		default:
+			diff = append(diff, DiffLine{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		diff = append(diff, DiffLine{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		diff = append(diff, DiffLine{'+', b[j]})
+	}
+
+	return diff
+}
+
+func (diff LineDiff) HasChanges() bool {
+	for _, line := range diff {
+		if line.Op != ' ' {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Print writes just the changed lines and diffContext lines around them.
+func (diff LineDiff) Print(w io.Writer) {
+	visible := make([]bool, len(diff))
+	for k, line := range diff {
+		if line.Op == ' ' {
+			continue
+		}
+
+		for i := k - diffContext; i <= k+diffContext; i++ {
+			if i >= 0 && i < len(diff) {
+				visible[i] = true
+			}
+		}
+	}
+
+	var skipped bool
+
+	for k, line := range diff {
+		if !visible[k] {
+			skipped = true
+			continue
+		}
+
+		if skipped {
+			fmt.Fprintln(w, "  ...")
+			skipped = false
+		}
+
+		fmt.Fprintf(w, "%c %s\n", line.Op, line.Text)
+	}
+}
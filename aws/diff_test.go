@@ -0,0 +1,80 @@
+package aws
+
+import (
+	"bytes"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want []string
+	}{
+		{name: "equal", a: "a b c", b: "a b c", want: []string{" a", " b", " c"}},
+		{name: "both empty", a: "", b: "", want: []string{}},
+		{name: "all added", a: "", b: "a b", want: []string{"+a", "+b"}},
+		{name: "all removed", a: "a b", b: "", want: []string{"-a", "-b"}},
+		{name: "changed line", a: "a b c", b: "a x c", want: []string{" a", "-b", "+x", " c"}},
+		{name: "insert in the middle", a: "a c", b: "a b c", want: []string{" a", "+b", " c"}},
+		{name: "remove at the end", a: "a b c", b: "a b", want: []string{" a", " b", "-c"}},
+		{name: "longest common subsequence", a: "a b c d e", b: "b c x e f", want: []string{"-a", " b", " c", "-d", "+x", " e", "+f"}},
+		{name: "moved line", a: "a b c", b: "c a b", want: []string{"+c", " a", " b", "-c"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diff := DiffLines(strings.Fields(test.a), strings.Fields(test.b))
+
+			got := make([]string, len(diff))
+			for k, line := range diff {
+				got[k] = string(line.Op) + line.Text
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+
+			hasChanges := test.a != test.b
+			if diff.HasChanges() != hasChanges {
+				t.Errorf("HasChanges is %v, want %v", diff.HasChanges(), hasChanges)
+			}
+		})
+	}
+}
+
+func TestLineDiffPrint(t *testing.T) {
+	a := make([]string, 0, 20)
+	for i := 1; i <= 20; i++ {
+		a = append(a, strconv.Itoa(i))
+	}
+
+	b := append([]string{}, a...)
+	b[5] = "six"
+	b = append(b, "21")
+
+	var buf bytes.Buffer
+	DiffLines(a, b).Print(&buf)
+
+	want := "  ...\n" +
+		"  3\n" +
+		"  4\n" +
+		"  5\n" +
+		"- 6\n" +
+		"+ six\n" +
+		"  7\n" +
+		"  8\n" +
+		"  9\n" +
+		"  ...\n" +
+		"  18\n" +
+		"  19\n" +
+		"  20\n" +
+		"+ 21\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
@@ -0,0 +1,376 @@
+package aws
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type (
+	// DotenvError is returned by ParseDotenv with the line of the problem.
+	DotenvError struct {
+		Line int
+		Msg  string
+	}
+
+	dotenvParser struct {
+		content     string
+		pos         int
+		line        int
+		interpolate bool
+		values      map[string]string
+	}
+)
+
+func (err *DotenvError) Error() string {
+	return fmt.Sprintf("line %d: %s", err.Line, err.Msg)
+}
+
+// ParseDotenv reads a .env file: blank lines and comments are ignored,
+// "export " prefix is accepted, values can be single quoted (literal), double
+// quoted (escape sequences and multi-line) or unquoted (inline comments after
+// a space). When interpolate is true ${VAR} and ${VAR:-default} are replaced
+// by earlier keys or variables from local environment.
+func ParseDotenv(reader io.Reader, interpolate bool) (map[string]string, error) {
+	content, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &dotenvParser{
+		content:     strings.Replace(string(content), "\r\n", "\n", -1),
+		line:        1,
+		interpolate: interpolate,
+		values:      make(map[string]string),
+	}
+
+	for {
+		p.skipBlankAndComments()
+		if p.eof() {
+			break
+		}
+
+		if err := p.parseStatement(); err != nil {
+			return nil, err
+		}
+	}
+
+	return p.values, nil
+}
+
+func (p *dotenvParser) eof() bool {
+	return p.pos >= len(p.content)
+}
+
+func (p *dotenvParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+
+	return p.content[p.pos]
+}
+
+func (p *dotenvParser) next() byte {
+	c := p.content[p.pos]
+	p.pos++
+
+	if c == '\n' {
+		p.line++
+	}
+
+	return c
+}
+
+func (p *dotenvParser) errorf(line int, format string, args ...interface{}) error {
+	return &DotenvError{
+		Line: line,
+		Msg:  fmt.Sprintf(format, args...),
+	}
+}
+
+func (p *dotenvParser) skipSpaces() {
+	for c := p.peek(); c == ' ' || c == '\t'; c = p.peek() {
+		p.next()
+	}
+}
+
+func (p *dotenvParser) skipToEndOfLine() {
+	for !p.eof() && p.peek() != '\n' {
+		p.next()
+	}
+}
+
+func (p *dotenvParser) skipBlankAndComments() {
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\t', '\n':
+			p.next()
+		case '#':
+			p.skipToEndOfLine()
+		default:
+			return
+		}
+	}
+}
+
+func isDotenvKeyChar(c byte, first bool) bool {
+	switch {
+	case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		return true
+	case !first && (c >= '0' && c <= '9' || c == '.' || c == '-'):
+		return true
+	}
+
+	return false
+}
+
+func (p *dotenvParser) readKey() string {
+	start := p.pos
+
+	for !p.eof() && isDotenvKeyChar(p.peek(), p.pos == start) {
+		p.next()
+	}
+
+	return p.content[start:p.pos]
+}
+
+func (p *dotenvParser) parseStatement() error {
+	line := p.line
+
+	key := p.readKey()
+	if key == "export" && (p.peek() == ' ' || p.peek() == '\t') {
+		p.skipSpaces()
+		key = p.readKey()
+	}
+
+	if strings.EqualFold("", key) {
+		return p.errorf(line, "invalid key, it needs to start with a letter or '_'")
+	}
+
+	p.skipSpaces()
+
+	switch p.peek() {
+	case '=':
+		p.next()
+	case 0, '\n', '#':
+		// KEY alone sets an empty value
+		p.skipToEndOfLine()
+		p.values[key] = ""
+		return nil
+	default:
+		return p.errorf(line, "expected '=' after key '%s'", key)
+	}
+
+	p.skipSpaces()
+
+	var (
+		value string
+		err   error
+	)
+
+	switch p.peek() {
+	case '\'':
+		value, err = p.readSingleQuoted(key)
+	case '"':
+		value, err = p.readDoubleQuoted(key)
+	default:
+		value, err = p.readUnquoted(key)
+	}
+	if err != nil {
+		return err
+	}
+
+	p.values[key] = value
+	return nil
+}
+
+func (p *dotenvParser) endQuotedValue(key string) error {
+	p.skipSpaces()
+
+	switch p.peek() {
+	case '#':
+		p.skipToEndOfLine()
+	case 0, '\n':
+	default:
+		return p.errorf(p.line, "unexpected characters after quoted value of '%s'", key)
+	}
+
+	return nil
+}
+
+func (p *dotenvParser) readSingleQuoted(key string) (string, error) {
+	line := p.line
+	p.next()
+
+	start := p.pos
+	for !p.eof() && p.peek() != '\'' {
+		p.next()
+	}
+
+	if p.eof() {
+		return "", p.errorf(line, "unterminated single quoted value of '%s'", key)
+	}
+
+	value := p.content[start:p.pos]
+	p.next()
+
+	return value, p.endQuotedValue(key)
+}
+
+func (p *dotenvParser) readDoubleQuoted(key string) (string, error) {
+	line := p.line
+	p.next()
+
+	var value strings.Builder
+
+	for {
+		if p.eof() {
+			return "", p.errorf(line, "unterminated double quoted value of '%s'", key)
+		}
+
+		c := p.next()
+
+		switch c {
+		case '"':
+			return value.String(), p.endQuotedValue(key)
+		case '\\':
+			if p.eof() {
+				return "", p.errorf(line, "unterminated double quoted value of '%s'", key)
+			}
+
+			escaped := p.next()
+			switch escaped {
+			case 'n':
+				value.WriteByte('\n')
+			case 'r':
+				value.WriteByte('\r')
+			case 't':
+				value.WriteByte('\t')
+			case '"', '\\', '$', '\'':
+				value.WriteByte(escaped)
+			case '\n':
+				// line continuation
+			default:
+				value.WriteByte('\\')
+				value.WriteByte(escaped)
+			}
+		case '$':
+			expanded, err := p.readVariable(c)
+			if err != nil {
+				return "", err
+			}
+
+			value.WriteString(expanded)
+		default:
+			value.WriteByte(c)
+		}
+	}
+}
+
+func (p *dotenvParser) readUnquoted(key string) (string, error) {
+	var value strings.Builder
+
+	for !p.eof() && p.peek() != '\n' {
+		c := p.peek()
+
+		// Inline comment needs a space before it, e.g. KEY=value # comment
+		if c == '#' && (value.Len() == 0 || strings.HasSuffix(value.String(), " ") || strings.HasSuffix(value.String(), "\t")) {
+			p.skipToEndOfLine()
+			break
+		}
+
+		p.next()
+
+		if c == '$' {
+			expanded, err := p.readVariable(c)
+			if err != nil {
+				return "", err
+			}
+
+			value.WriteString(expanded)
+			continue
+		}
+
+		value.WriteByte(c)
+	}
+
+	return strings.TrimSpace(value.String()), nil
+}
+
+// readVariable is called after '$', it expands ${VAR} or ${VAR:-default} when
+// interpolation is enabled, otherwise returns the text as it is.
+func (p *dotenvParser) readVariable(dollar byte) (string, error) {
+	if !p.interpolate || p.peek() != '{' {
+		return string(dollar), nil
+	}
+
+	line := p.line
+
+	end := strings.IndexByte(p.content[p.pos:], '}')
+	if end < 0 || strings.ContainsRune(p.content[p.pos:p.pos+end], '\n') {
+		return "", p.errorf(line, "unterminated variable, missing '}'")
+	}
+
+	expr := p.content[p.pos+1 : p.pos+end]
+	for i := 0; i <= end; i++ {
+		p.next()
+	}
+
+	name := expr
+	var (
+		def    string
+		hasDef bool
+	)
+	if i := strings.Index(expr, ":-"); i >= 0 {
+		name, def, hasDef = expr[:i], expr[i+2:], true
+	}
+
+	if strings.EqualFold("", name) {
+		return "", p.errorf(line, "empty variable name in '${%s}'", expr)
+	}
+
+	if value, ok := p.values[name]; ok && (!hasDef || value != "") {
+		return value, nil
+	}
+	if value, ok := os.LookupEnv(name); ok && (!hasDef || value != "") {
+		return value, nil
+	}
+	if hasDef {
+		return def, nil
+	}
+
+	return "", p.errorf(line, "variable '%s' is not defined, use ${%s:-default} for optional ones", name, name)
+}
+
+var (
+	dotenvPlainValue = regexp.MustCompile(`^[a-zA-Z0-9_./:@,+=-]*$`)
+	dotenvEscaper    = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`, "$", `\$`)
+)
+
+// FormatDotenv writes envvars sorted by key in a format read by ParseDotenv,
+// values are double quoted when needed.
+func FormatDotenv(envvars map[string]string) string {
+	keys := make([]string, 0, len(envvars))
+	for key := range envvars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var buf strings.Builder
+
+	for _, key := range keys {
+		value := envvars[key]
+
+		if !dotenvPlainValue.MatchString(value) {
+			value = `"` + dotenvEscaper.Replace(value) + `"`
+		}
+
+		buf.WriteString(key + "=" + value + "\n")
+	}
+
+	return buf.String()
+}
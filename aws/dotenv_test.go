@@ -0,0 +1,151 @@
+package aws
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDotenv(t *testing.T) {
+	os.Setenv("DEPLOY_ECS_TEST_HOST", "db.local")
+	defer os.Unsetenv("DEPLOY_ECS_TEST_HOST")
+
+	tests := []struct {
+		name        string
+		content     string
+		interpolate bool
+		want        map[string]string
+	}{
+		{
+			name:    "unquoted",
+			content: "A=1\nB = two words \n\n# comment\nC=\n",
+			want:    map[string]string{"A": "1", "B": "two words", "C": ""},
+		},
+		{
+			name:    "export and key alone",
+			content: "export A=1\nB\n",
+			want:    map[string]string{"A": "1", "B": ""},
+		},
+		{
+			name:    "inline comment needs a space",
+			content: "A=value # comment\nB=color#fff\n",
+			want:    map[string]string{"A": "value", "B": "color#fff"},
+		},
+		{
+			name:    "single quoted is literal",
+			content: `A='a \n $B "c"' # comment`,
+			want:    map[string]string{"A": `a \n $B "c"`},
+		},
+		{
+			name:    "double quoted escapes",
+			content: `A="line1\nline2\t\"q\" \$HOME \\ \x"`,
+			want:    map[string]string{"A": "line1\nline2\t\"q\" $HOME \\ \\x"},
+		},
+		{
+			name:    "double quoted multi-line",
+			content: "A=\"first\nsecond\"\nB=2\n",
+			want:    map[string]string{"A": "first\nsecond", "B": "2"},
+		},
+		{
+			name:    "windows line endings",
+			content: "A=1\r\nB=\"2\"\r\n",
+			want:    map[string]string{"A": "1", "B": "2"},
+		},
+		{
+			name:    "variables are kept without interpolate",
+			content: "A=${B}\nC=\"$D\"\n",
+			want:    map[string]string{"A": "${B}", "C": "$D"},
+		},
+		{
+			name:        "interpolate earlier keys and local env",
+			content:     "USER=app\nURL=postgres://${USER}@${DEPLOY_ECS_TEST_HOST}/db\nQ=\"${USER}\"\nS='${USER}'\n",
+			interpolate: true,
+			want: map[string]string{
+				"USER": "app",
+				"URL":  "postgres://app@db.local/db",
+				"Q":    "app",
+				"S":    "${USER}",
+			},
+		},
+		{
+			name:        "interpolate default",
+			content:     "EMPTY=\nA=${DEPLOY_ECS_TEST_MISSING:-fallback}\nB=${EMPTY:-used}\nC=${DEPLOY_ECS_TEST_HOST:-unused}\n",
+			interpolate: true,
+			want:        map[string]string{"EMPTY": "", "A": "fallback", "B": "used", "C": "db.local"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseDotenv(strings.NewReader(test.content), test.interpolate)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseDotenvErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		interpolate bool
+		line        int
+	}{
+		{name: "invalid key", content: "A=1\n1A=2\n", line: 2},
+		{name: "missing equal", content: "A 1\n", line: 1},
+		{name: "unterminated single quote", content: "A=1\nB='open\n", line: 2},
+		{name: "unterminated double quote", content: "A=\"open\nstill open\n", line: 1},
+		{name: "characters after quote", content: "A=\"x\" y\n", line: 1},
+		{name: "undefined variable", content: "\nA=${DEPLOY_ECS_TEST_MISSING}\n", interpolate: true, line: 2},
+		{name: "unterminated variable", content: "A=${B\n", interpolate: true, line: 1},
+		{name: "empty variable", content: "A=${:-x}\n", interpolate: true, line: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseDotenv(strings.NewReader(test.content), test.interpolate)
+
+			dotenvErr, ok := err.(*DotenvError)
+			if !ok {
+				t.Fatalf("got %v, want a DotenvError", err)
+			}
+			if dotenvErr.Line != test.line {
+				t.Errorf("got line %d, want %d: %s", dotenvErr.Line, test.line, err)
+			}
+		})
+	}
+}
+
+func TestFormatDotenv(t *testing.T) {
+	envvars := map[string]string{
+		"PLAIN":     "postgres://user@host:5432/db",
+		"EMPTY":     "",
+		"SPACES":    "two words",
+		"MULTILINE": "a\nb",
+		"SPECIAL":   `"quoted" $HOME \ `,
+	}
+
+	content := FormatDotenv(envvars)
+
+	want := "EMPTY=\n" +
+		"MULTILINE=\"a\\nb\"\n" +
+		"PLAIN=postgres://user@host:5432/db\n" +
+		"SPACES=\"two words\"\n" +
+		"SPECIAL=\"\\\"quoted\\\" \\$HOME \\\\ \"\n"
+	if content != want {
+		t.Errorf("got %q, want %q", content, want)
+	}
+
+	got, err := ParseDotenv(strings.NewReader(content), true)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(got, envvars) {
+		t.Errorf("got %q, want %q", got, envvars)
+	}
+}
@@ -0,0 +1,347 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/ecs"
+	yaml "gopkg.in/yaml.v3"
+)
+
+const (
+	EnvTypeString   = "string"
+	EnvTypeInt      = "int"
+	EnvTypeNumber   = "number"
+	EnvTypeBool     = "bool"
+	EnvTypeURL      = "url"
+	EnvTypeDuration = "duration"
+	EnvTypeJSON     = "json"
+)
+
+// envSchemaFilename is looked up from current dir to the root of the project
+// and in the home dir.
+const envSchemaFilename = ".deploy-ecs-env"
+
+type (
+	// EnvSchema tells which env vars a service needs, e.g.
+	//
+	//   my-service:
+	//     container: app
+	//     required: [DATABASE_URL]
+	//     keys:
+	//       LOG_LEVEL: {values: [debug, info, error]}
+	//       PORT: {type: int, required: true}
+	EnvSchema struct {
+		// Container validated, it can be omitted when the task has a single
+		// container or one named as the service
+		Container string                  `yaml:"container" json:"container"`
+		Required  []string                `yaml:"required" json:"required"`
+		Keys      map[string]EnvKeySchema `yaml:"keys" json:"keys"`
+	}
+
+	// EnvKeySchema validates value of a key, a secret is accepted as any value.
+	EnvKeySchema struct {
+		Required bool     `yaml:"required" json:"required"`
+		Type     string   `yaml:"type" json:"type"`
+		Values   []string `yaml:"values" json:"values"`
+		Pattern  string   `yaml:"pattern" json:"pattern"`
+	}
+
+	// EnvSchemaFile has schemas by service name, or pattern as *-my-service to
+	// match namespaced services.
+	EnvSchemaFile map[string]EnvSchema
+)
+
+// findEnvSchemaFile returns the first schema file from dir up to the root of
+// the project (where .git is) or of the filesystem.
+func findEnvSchemaFile(dir string) string {
+	for {
+		if filename := existingSchemaFile(dir); !strings.EqualFold("", filename) {
+			return filename
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return ""
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+func existingSchemaFile(dir string) string {
+	for _, ext := range []string{".yaml", ".yml", ".json"} {
+		filename := filepath.Join(dir, envSchemaFilename+ext)
+		if _, err := os.Stat(filename); err == nil {
+			return filename
+		}
+	}
+
+	return ""
+}
+
+// GetEnvSchemaFilenames returns schema files in use, project one first.
+func GetEnvSchemaFilenames() []string {
+	filenames := make([]string, 0, 2)
+
+	if dir, err := os.Getwd(); err == nil {
+		if filename := findEnvSchemaFile(dir); !strings.EqualFold("", filename) {
+			filenames = append(filenames, filename)
+		}
+	}
+	if u, err := user.Current(); err == nil {
+		if filename := existingSchemaFile(u.HomeDir); !strings.EqualFold("", filename) && !containsString(filenames, filename) {
+			filenames = append(filenames, filename)
+		}
+	}
+
+	return filenames
+}
+
+func readEnvSchemaFile(filename string) EnvSchemaFile {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		fmt.Printf("Cannot read '%s': %s\n", filename, err)
+		os.Exit(1)
+	}
+
+	schemas := make(EnvSchemaFile)
+
+	if strings.EqualFold(".json", filepath.Ext(filename)) {
+		err = json.Unmarshal(content, &schemas)
+	} else {
+		err = yaml.Unmarshal(content, &schemas)
+	}
+	if err != nil {
+		fmt.Printf("Cannot parse '%s': %s\n", filename, err)
+		os.Exit(1)
+	}
+
+	for service, schema := range schemas {
+		if err := schema.check(); err != nil {
+			fmt.Printf("Invalid env schema of '%s' in '%s': %s\n", service, filename, err)
+			os.Exit(1)
+		}
+	}
+
+	return schemas
+}
+
+// get returns the schema of service, exact names have priority over patterns.
+func (schemas EnvSchemaFile) get(service string) (EnvSchema, bool) {
+	if schema, ok := schemas[service]; ok {
+		return schema, true
+	}
+
+	patterns := make([]string, 0, len(schemas))
+	for pattern := range schemas {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, service); ok {
+			return schemas[pattern], true
+		}
+	}
+
+	return EnvSchema{}, false
+}
+
+// GetEnvSchema returns the schema of service, the project file has priority
+// over the one in home dir. It returns nil when service has no schema.
+func GetEnvSchema(service string) *EnvSchema {
+	for _, filename := range GetEnvSchemaFilenames() {
+		if schema, ok := readEnvSchemaFile(filename).get(service); ok {
+			return &schema
+		}
+	}
+
+	return nil
+}
+
+func (schema EnvSchema) check() error {
+	for key, keySchema := range schema.Keys {
+		switch keySchema.Type {
+		case "", EnvTypeString, EnvTypeInt, EnvTypeNumber, EnvTypeBool, EnvTypeURL, EnvTypeDuration, EnvTypeJSON:
+		default:
+			return fmt.Errorf("key '%s' has invalid type '%s', use: %s, %s, %s, %s, %s, %s or %s", key, keySchema.Type,
+				EnvTypeString, EnvTypeInt, EnvTypeNumber, EnvTypeBool, EnvTypeURL, EnvTypeDuration, EnvTypeJSON)
+		}
+
+		if !strings.EqualFold("", keySchema.Pattern) {
+			if _, err := regexp.Compile(keySchema.Pattern); err != nil {
+				return fmt.Errorf("key '%s' has invalid pattern: %s", key, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func checkEnvType(typ, value string) error {
+	var err error
+
+	switch typ {
+	case EnvTypeInt:
+		_, err = strconv.ParseInt(value, 10, 64)
+	case EnvTypeNumber:
+		_, err = strconv.ParseFloat(value, 64)
+	case EnvTypeBool:
+		_, err = strconv.ParseBool(value)
+	case EnvTypeDuration:
+		_, err = time.ParseDuration(value)
+	case EnvTypeJSON:
+		if !json.Valid([]byte(value)) {
+			return fmt.Errorf("is not a valid json")
+		}
+	case EnvTypeURL:
+		var u *url.URL
+		u, err = url.Parse(value)
+		if err == nil && (strings.EqualFold("", u.Scheme) || strings.EqualFold("", u.Host)) {
+			return fmt.Errorf("is not an absolute url")
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("is not a valid %s", typ)
+	}
+
+	return nil
+}
+
+// Validate returns what is wrong in env vars, secrets only need to exist since
+// their values aren't in the task definition.
+func (schema EnvSchema) Validate(envvars, secrets map[string]string) []string {
+	violations := make([]string, 0)
+
+	required := append([]string{}, schema.Required...)
+	for key, keySchema := range schema.Keys {
+		if keySchema.Required && !containsString(required, key) {
+			required = append(required, key)
+		}
+	}
+
+	for _, key := range required {
+		_, isEnvvar := envvars[key]
+		_, isSecret := secrets[key]
+		if !isEnvvar && !isSecret {
+			violations = append(violations, fmt.Sprintf("%s is required", key))
+		}
+	}
+
+	for key, keySchema := range schema.Keys {
+		value, ok := envvars[key]
+		if !ok {
+			continue
+		}
+
+		if len(keySchema.Values) > 0 && !containsString(keySchema.Values, value) {
+			violations = append(violations, fmt.Sprintf("%s must be one of: %s", key, strings.Join(keySchema.Values, ", ")))
+			continue
+		}
+		if !strings.EqualFold("", keySchema.Pattern) && !regexp.MustCompile(keySchema.Pattern).MatchString(value) {
+			violations = append(violations, fmt.Sprintf("%s must match '%s'", key, keySchema.Pattern))
+			continue
+		}
+		if err := checkEnvType(keySchema.Type, value); err != nil {
+			violations = append(violations, fmt.Sprintf("%s %s", key, err))
+		}
+	}
+
+	sort.Strings(violations)
+	return violations
+}
+
+// getContainer returns the container validated by schema, nil when it's not
+// in the task definition.
+func (schema EnvSchema) getContainer(taskDefinition *ecs.TaskDefinition) *ecs.ContainerDefinition {
+	if !strings.EqualFold("", schema.Container) {
+		return findContainerDefinition(taskDefinition, schema.Container)
+	}
+	if len(taskDefinition.ContainerDefinitions) == 1 {
+		return taskDefinition.ContainerDefinitions[0]
+	}
+
+	return findContainerDefinition(taskDefinition, *taskDefinition.Family)
+}
+
+// EnvSchemaReport returns what is wrong in env vars of taskDefinition against
+// the schema of service, empty when they match it.
+func EnvSchemaReport(service string, taskDefinition *ecs.TaskDefinition) string {
+	schema := GetEnvSchema(service)
+	if schema == nil {
+		return ""
+	}
+
+	def := schema.getContainer(taskDefinition)
+	if def == nil {
+		fmt.Printf("# Env schema of '%s' was not checked, set which container it validates\n", service)
+		return ""
+	}
+
+	violations := schema.Validate(envvarsToMap(def.Environment), secretsToMap(def.Secrets))
+	if len(violations) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("Env vars of '%s' container[%s] don't match its schema:\n  - %s", service, *def.Name, strings.Join(violations, "\n  - "))
+}
+
+// CheckEnvSchemaReports prints every report, so all services are shown at
+// once. Reports stop the command unless force is true.
+func CheckEnvSchemaReports(reports []string, force bool) {
+	if len(reports) == 0 {
+		return
+	}
+
+	fmt.Println(strings.Join(reports, "\n"))
+
+	if !force {
+		fmt.Println("Use --force to ignore it")
+		os.Exit(1)
+	}
+
+	fmt.Println("# Ignoring it because of --force")
+}
+
+// checkEnvSchema validates env vars of taskDefinition against the schema of
+// service. Violations stop the command unless force is true.
+func checkEnvSchema(service string, taskDefinition *ecs.TaskDefinition, force bool) {
+	if report := EnvSchemaReport(service, taskDefinition); !strings.EqualFold("", report) {
+		CheckEnvSchemaReports([]string{report}, force)
+	}
+}
+
+// RevisionEnvSchemaReport does the same as EnvSchemaReport to the revision
+// (arn) of service.
+func (sess *AWSSession) RevisionEnvSchemaReport(service, taskDefinitionArn string) string {
+	if GetEnvSchema(service) == nil {
+		return ""
+	}
+
+	revision, _ := strconv.ParseInt(getRevisionFromTaskDefinition(taskDefinitionArn), 10, 64)
+	taskDefinition := DescribeTaskDefinition(sess.Client, getFamilyFromTaskDefinition(taskDefinitionArn), revision)
+
+	return EnvSchemaReport(service, taskDefinition)
+}
+
+// ValidateEnvSchema checks the revision (arn) of service before it's
+// deployed. Violations stop the command unless force is true.
+func (sess *AWSSession) ValidateEnvSchema(service, taskDefinitionArn string, force bool) {
+	if report := sess.RevisionEnvSchemaReport(service, taskDefinitionArn); !strings.EqualFold("", report) {
+		CheckEnvSchemaReports([]string{report}, force)
+	}
+}
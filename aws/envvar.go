@@ -6,11 +6,13 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ecs"
+	"golang.org/x/crypto/ssh/terminal"
 )
 
 func inArray(element string, array []string) bool {
@@ -27,196 +29,478 @@ func inArray(element string, array []string) bool {
 	return false
 }
 
-func readEnvvarFile(reader *bufio.Reader) map[string]string {
-	envvars := make(map[string]string)
+// containsString is like inArray, but it's case sensitive and false for an
+// empty array.
+func containsString(array []string, value string) bool {
+	for _, v := range array {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// readEnvvarFile parses reader as a .env file, see ParseDotenv.
+func readEnvvarFile(reader io.Reader, interpolate bool) map[string]string {
+	envvars, err := ParseDotenv(reader, interpolate)
+	if err != nil {
+		fmt.Println("Cannot parse stdin as .env:", err)
+		os.Exit(1)
+	}
+
+	return envvars
+}
+
+// readEnvvarFileAsJson parses reader as a JSON object, lines starting with #
+// are ignored. See ParseJSONEnvvars.
+func readEnvvarFileAsJson(reader *bufio.Reader, options EnvFileOptions) map[string]string {
+	var content string
 
 	for {
 		input, err := reader.ReadString('\n')
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-
+		if err != nil && err != io.EOF {
 			fmt.Println("Cannot read from stdin:", err)
 			os.Exit(1)
 		}
 
-		input = strings.TrimSpace(input)
-		if strings.HasPrefix(input, "#") {
-			continue
+		if !strings.HasPrefix(strings.TrimSpace(input), "#") {
+			content += input
 		}
 
-		parts := strings.SplitN(input, "=", 2)
-
-		var value string
-		if len(parts) > 1 {
-			value = parts[1]
+		if err == io.EOF {
+			break
 		}
+	}
 
-		envvars[parts[0]] = value
+	envvars, err := ParseJSONEnvvars(strings.NewReader(content), options)
+	if err != nil {
+		fmt.Println("Cannot parse stdin as json:", err)
+		os.Exit(1)
 	}
 
 	return envvars
 }
 
-func readEnvvarFileAsJson(reader *bufio.Reader) map[string]string {
-	envvars := make(map[string]string)
+type (
+	// EnvvarChanges has keys added, changed and removed in env vars.
+	EnvvarChanges struct {
+		Added   []string
+		Changed []string
+		Removed []string
+	}
+)
 
-	var content string
+func (changes EnvvarChanges) HasChanges() bool {
+	return len(changes.Added) > 0 || len(changes.Changed) > 0 || len(changes.Removed) > 0
+}
 
-	for {
-		input, err := reader.ReadString('\n')
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
+func (changes EnvvarChanges) String() string {
+	if !changes.HasChanges() {
+		return "no changes"
+	}
 
-			fmt.Println("Cannot read from stdin:", err)
-			os.Exit(1)
+	parts := make([]string, 0, 3)
+	for _, group := range []struct {
+		name string
+		keys []string
+	}{
+		{"added", changes.Added},
+		{"changed", changes.Changed},
+		{"removed", changes.Removed},
+	} {
+		if len(group.keys) > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s (%s)", len(group.keys), group.name, strings.Join(group.keys, ", ")))
 		}
+	}
+
+	return strings.Join(parts, ", ")
+}
 
-		input = strings.TrimSpace(input)
-		if strings.HasPrefix(input, "#") {
+// readEnvvars returns env vars of def in gets (all when it's empty). Secrets
+// are read from SSM or Secrets Manager when reveal is true, otherwise they're
+// returned apart as "KEY from source".
+func (sess *AWSSession) readEnvvars(def *ecs.ContainerDefinition, gets []string, reveal bool) (map[string]string, []string) {
+	envvars := make(map[string]string)
+	for _, envvar := range def.Environment {
+		if inArray(*envvar.Name, gets) {
+			envvars[*envvar.Name] = *envvar.Value
+		}
+	}
+
+	secrets := make([]string, 0, len(def.Secrets))
+	for _, secret := range def.Secrets {
+		if !inArray(*secret.Name, gets) {
 			continue
 		}
 
-		content += input + "\n"
-	}
+		if !reveal {
+			secrets = append(secrets, fmt.Sprintf("%s from %s", *secret.Name, GetSecretSource(*secret.ValueFrom)))
+			continue
+		}
 
-	var jsonFile map[string]interface{}
+		value, err := sess.RevealSecret(*secret.ValueFrom)
+		if err != nil {
+			fmt.Printf("Cannot read secret '%s' from %s: %s\n", *secret.Name, GetSecretSource(*secret.ValueFrom), err)
+			os.Exit(1)
+		}
 
-	err := json.Unmarshal([]byte(content), &jsonFile)
-	if err != nil {
-		fmt.Println("Cannot parse stdin as json:", err)
-		os.Exit(1)
+		envvars[*secret.Name] = value
 	}
+	sort.Strings(secrets)
 
-	for k, v := range jsonFile {
-		envvars[k] = fmt.Sprintf("%v", v)
-	}
+	return envvars, secrets
+}
 
-	return envvars
+// GetEnvvarValues returns env vars of container as the service sees them,
+// secrets are resolved only when resolveSecrets is true, otherwise they're
+// returned apart as "KEY from source".
+func (sess *AWSSession) GetEnvvarValues(service string, revision int64, container string, resolveSecrets bool) (map[string]string, []string) {
+	taskDefinition := DescribeTaskDefinition(sess.Client, service, revision)
+	return sess.readEnvvars(GetContainerDefinition(taskDefinition, container), nil, resolveSecrets)
 }
 
-func (sess *AWSSession) GetEnvvar(service string, revision int64, gets []string, formatJson bool) {
+// GetEnvvar shows env vars of container, sensitive values are masked and
+// secrets are listed as comments with their source unless reveal is true.
+func (sess *AWSSession) GetEnvvar(service string, revision int64, container string, gets []string, formatJson, reveal bool) {
 	taskDefinition := DescribeTaskDefinition(sess.Client, service, revision)
+	def := GetContainerDefinition(taskDefinition, container)
 
-	for _, def := range taskDefinition.ContainerDefinitions {
-		envvars := make(map[string]string)
-		for _, envvar := range def.Environment {
-			if inArray(*envvar.Name, gets) {
-				envvars[*envvar.Name] = *envvar.Value
-			}
-		}
+	envvars, secrets := sess.readEnvvars(def, gets, reveal)
 
-		msg := fmt.Sprintf("# Getting env-vars of '%s'", service)
-		if revision != 0 {
-			msg += fmt.Sprintf(" revision[%d]", revision)
-		}
-		msg += ":"
+	envvars, masked := sess.masker(reveal).MaskEnvvars(envvars)
 
-		if formatJson {
-			msg += "\n"
-			j, _ := json.MarshalIndent(&envvars, "", "   ")
-			msg += string(j)
-		} else {
-			keys := make([]string, 0, len(envvars))
+	msg := fmt.Sprintf("# Getting env-vars of '%s'", service)
+	if len(taskDefinition.ContainerDefinitions) > 1 {
+		msg += fmt.Sprintf(" container[%s]", *def.Name)
+	}
+	if revision != 0 {
+		msg += fmt.Sprintf(" revision[%d]", revision)
+	}
+	msg += ":"
 
-			for k := range envvars {
-				keys = append(keys, k)
-			}
-			sort.Strings(keys)
+	if formatJson {
+		msg += "\n"
+		j, _ := json.MarshalIndent(&envvars, "", "   ")
+		msg += string(j)
+	} else {
+		keys := make([]string, 0, len(envvars))
 
-			for _, key := range keys {
-				msg += fmt.Sprintf("\n%s=%s", key, envvars[key])
-			}
+		for k := range envvars {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			msg += fmt.Sprintf("\n%s=%s", key, envvars[key])
 		}
+	}
+
+	var notes string
+	if masked > 0 {
+		notes += fmt.Sprintf("\n# %d sensitive value(s) masked (use --reveal to show them)", masked)
+	}
+	if len(secrets) > 0 {
+		notes += "\n# Secrets (use --reveal to show values):\n# " + strings.Join(secrets, "\n# ")
+	}
 
+	if formatJson {
+		// Notes after the JSON would break who parses it
 		fmt.Println(msg)
+		if !strings.EqualFold("", notes) {
+			fmt.Fprintln(os.Stderr, strings.TrimPrefix(notes, "\n"))
+		}
 		return
 	}
+
+	fmt.Println(msg + notes)
 }
 
-func (sess *AWSSession) DiffEnvvarFromFile(service string, revision int64, file *os.File, formatJson bool) (map[string]string, map[string]struct{}) {
+// ReadEnvvarFile reads a .env file, or a JSON object when options.Json is
+// true, it exits when the file is not valid.
+func ReadEnvvarFile(file io.Reader, options EnvFileOptions) map[string]string {
+	reader := bufio.NewReader(file)
+	if options.Json {
+		return readEnvvarFileAsJson(reader, options)
+	}
+
+	return readEnvvarFile(reader, options.Interpolate)
+}
+
+func (sess *AWSSession) DiffEnvvarFromFile(service string, revision int64, container string, file *os.File, options EnvFileOptions) (map[string]string, map[string]struct{}) {
+	changes, unsets, _ := sess.DiffEnvvars(service, revision, container, ReadEnvvarFile(file, options))
+	return changes, unsets
+}
+
+// DiffEnvvars returns changes and unsets to replace env vars of container by
+// envvars, and a summary of what would change. envvars is not changed, so
+// it can be applied to several services.
+func (sess *AWSSession) DiffEnvvars(service string, revision int64, container string, envvars map[string]string) (map[string]string, map[string]struct{}, EnvvarChanges) {
 	taskDefinition := DescribeTaskDefinition(sess.Client, service, revision)
+	def := GetContainerDefinition(taskDefinition, container)
 
-	var changes map[string]string
+	current := envvarsToMap(def.Environment)
+	secrets := secretsToMap(def.Secrets)
 
-	// Read envvar from file
-	reader := bufio.NewReader(file)
-	if formatJson {
-		changes = readEnvvarFileAsJson(reader)
-	} else {
-		changes = readEnvvarFile(reader)
+	var summary EnvvarChanges
+
+	changes := make(map[string]string, len(envvars))
+	for key, value := range envvars {
+		changes[key] = value
+
+		currentValue, isEnvvar := current[key]
+		_, isSecret := secrets[key]
+
+		switch {
+		case isSecret || (isEnvvar && currentValue != value):
+			summary.Changed = append(summary.Changed, key)
+		case !isEnvvar:
+			summary.Added = append(summary.Added, key)
+		}
 	}
 
 	unsets := make(map[string]struct{}, 0)
 
-	for _, def := range taskDefinition.ContainerDefinitions {
-		for _, envvar := range def.Environment {
-			if _, ok := changes[*envvar.Name]; !ok {
-				if _, ok := unsets[*envvar.Name]; !ok {
-					unsets[*envvar.Name] = struct{}{}
-				}
-			}
+	for key := range current {
+		if _, ok := changes[key]; !ok {
+			unsets[key] = struct{}{}
+			summary.Removed = append(summary.Removed, key)
 		}
 	}
 
-	return changes, unsets
+	sort.Strings(summary.Added)
+	sort.Strings(summary.Changed)
+	sort.Strings(summary.Removed)
+
+	return changes, unsets, summary
+}
+
+// UpdateEnvvar changes env vars of container and registers a new revision.
+// secrets maps a variable to the valueFrom of a secret, a variable is either
+// plain or secret so setting one removes the other. The new revision is not
+// registered when it doesn't match the env schema of service unless force is
+// true.
+func (sess *AWSSession) UpdateEnvvar(service string, revision int64, container string, changes map[string]string, unsets map[string]struct{}, secrets map[string]string, force bool) int64 {
+	taskDefinition := sess.PrepareEnvvarUpdate(service, revision, container, changes, unsets, secrets)
+	if taskDefinition == nil {
+		return 0
+	}
+
+	checkEnvSchema(service, taskDefinition, force)
+
+	return sess.RegisterEnvvarUpdate(taskDefinition)
 }
 
-func (sess *AWSSession) UpdateEnvvar(service string, revision int64, changes map[string]string, unsets map[string]struct{}) int64 {
+// PrepareEnvvarUpdate returns the task definition with env vars of container
+// changed as UpdateEnvvar does, without registering it. nil is returned when
+// nothing changes.
+func (sess *AWSSession) PrepareEnvvarUpdate(service string, revision int64, container string, changes map[string]string, unsets map[string]struct{}, secrets map[string]string) *ecs.TaskDefinition {
 	taskDefinition := DescribeTaskDefinition(sess.Client, service, revision)
+	def := GetContainerDefinition(taskDefinition, container)
 
 	var hasChanged bool
 
-	for k, def := range taskDefinition.ContainerDefinitions {
-		envvars := make([]*ecs.KeyValuePair, 0, len(def.Environment))
+	envvars := make([]*ecs.KeyValuePair, 0, len(def.Environment))
+
+	currentChanges := make(map[string]string, len(changes))
+	for k, v := range changes {
+		currentChanges[k] = v
+	}
 
-		currentChanges := make(map[string]string, len(changes))
-		for k, v := range changes {
-			currentChanges[k] = v
+	for _, envvar := range def.Environment {
+		newValue, ok := currentChanges[*envvar.Name]
+		if ok {
+			if aws.StringValue(envvar.Value) != newValue {
+				envvar.SetValue(newValue)
+				hasChanged = true
+			}
+
+			delete(currentChanges, *envvar.Name)
 		}
 
-		for _, envvar := range def.Environment {
-			newValue, ok := currentChanges[*envvar.Name]
-			if ok {
-				if !strings.EqualFold(*envvar.Value, newValue) {
-					envvar.SetValue(newValue)
-					hasChanged = true
-				}
+		if _, ok = unsets[*envvar.Name]; ok {
+			hasChanged = true
+			continue
+		}
+		if _, ok = secrets[*envvar.Name]; ok {
+			hasChanged = true
+			continue
+		}
 
-				delete(currentChanges, *envvar.Name)
-			}
+		envvars = append(envvars, envvar)
+	}
+
+	if len(currentChanges) > 0 {
+		// Some fields that didn't exist need to be add
+		hasChanged = true
+	}
+
+	for name, value := range currentChanges {
+		envvars = append(envvars, &ecs.KeyValuePair{
+			Name:  aws.String(name),
+			Value: aws.String(value),
+		})
+	}
+
+	def.Environment = envvars
 
-			if _, ok = unsets[*envvar.Name]; ok {
+	newSecrets := make([]*ecs.Secret, 0, len(def.Secrets)+len(secrets))
+
+	currentSecrets := make(map[string]string, len(secrets))
+	for k, v := range secrets {
+		currentSecrets[k] = v
+	}
+
+	for _, secret := range def.Secrets {
+		valueFrom, ok := currentSecrets[*secret.Name]
+		if ok {
+			if *secret.ValueFrom != valueFrom {
+				secret.SetValueFrom(valueFrom)
 				hasChanged = true
-				continue
 			}
 
-			envvars = append(envvars, envvar)
+			delete(currentSecrets, *secret.Name)
 		}
 
-		if len(currentChanges) > 0 {
-			// Some fields that didn't exist need to be add
+		_, isUnset := unsets[*secret.Name]
+		_, isPlain := changes[*secret.Name]
+		if isUnset || isPlain {
 			hasChanged = true
+			continue
 		}
 
-		for name, value := range currentChanges {
-			envvars = append(envvars, &ecs.KeyValuePair{
-				Name:  aws.String(name),
-				Value: aws.String(value),
-			})
-		}
+		newSecrets = append(newSecrets, secret)
+	}
+
+	if len(currentSecrets) > 0 {
+		hasChanged = true
+	}
 
-		taskDefinition.ContainerDefinitions[k].Environment = envvars
+	for name, valueFrom := range currentSecrets {
+		newSecrets = append(newSecrets, &ecs.Secret{
+			Name:      aws.String(name),
+			ValueFrom: aws.String(valueFrom),
+		})
+	}
+
+	if len(newSecrets) > 0 {
+		def.Secrets = newSecrets
+	} else {
+		def.Secrets = nil
 	}
 
 	if !hasChanged {
 		fmt.Println("Nothing to update in this task definition")
-		return 0
+		return nil
 	}
 
-	taskDefinition = RegisterTaskDefinition(sess.Client, service, taskDefinition.ContainerDefinitions)
+	return taskDefinition
+}
+
+// RegisterEnvvarUpdate registers taskDefinition returned by
+// PrepareEnvvarUpdate and returns its revision.
+func (sess *AWSSession) RegisterEnvvarUpdate(taskDefinition *ecs.TaskDefinition) int64 {
+	for _, def := range taskDefinition.ContainerDefinitions {
+		if len(def.Secrets) > 0 {
+			checkExecutionRole(taskDefinition)
+			break
+		}
+	}
+
+	taskDefinition = RegisterTaskDefinition(sess.Client, taskDefinition)
 	return *taskDefinition.Revision
 }
+
+// FormatEnvvarFile returns env vars of container as a .env file to be edited,
+// secrets are listed as comments since they're not changed by it.
+func (sess *AWSSession) FormatEnvvarFile(service string, revision int64, container string) string {
+	taskDefinition := DescribeTaskDefinition(sess.Client, service, revision)
+	def := GetContainerDefinition(taskDefinition, container)
+
+	envvars := make(map[string]string, len(def.Environment))
+	for _, envvar := range def.Environment {
+		envvars[*envvar.Name] = *envvar.Value
+	}
+
+	content := fmt.Sprintf("# Env vars of '%s' container[%s] revision[%d]\n", service, *def.Name, *taskDefinition.Revision)
+	content += "# Lines starting with # are ignored, removed keys will be unset\n"
+
+	for _, secret := range def.Secrets {
+		content += fmt.Sprintf("# secret %s from %s\n", *secret.Name, GetSecretSource(*secret.ValueFrom))
+	}
+
+	return content + "\n" + FormatDotenv(envvars)
+}
+
+// PreviewEnvvarChanges shows keys added, removed or changed by changes and
+// unsets, it returns false when nothing would change.
+func (sess *AWSSession) PreviewEnvvarChanges(service string, revision int64, container string, changes map[string]string, unsets map[string]struct{}, reveal bool) bool {
+	taskDefinition := DescribeTaskDefinition(sess.Client, service, revision)
+	def := GetContainerDefinition(taskDefinition, container)
+
+	before := envvarsToMap(def.Environment)
+
+	after := make(map[string]string, len(before)+len(changes))
+	for key, value := range before {
+		if _, ok := unsets[key]; !ok {
+			after[key] = value
+		}
+	}
+	for key, value := range changes {
+		after[key] = value
+	}
+
+	diffs := compareEnvvars("", before, after, sess.masker(reveal))
+	if len(diffs) == 0 {
+		return false
+	}
+
+	fmt.Printf("Changes to env vars of '%s' container[%s] revision[%d]:\n", service, *def.Name, *taskDefinition.Revision)
+	printFieldDiffs(diffs, terminal.IsTerminal(int(os.Stdout.Fd())))
+
+	return true
+}
+
+// FilterEnvvars returns env vars which match any include pattern (all when
+// there is none) and don't match any exclude pattern, e.g. DATABASE_*.
+func FilterEnvvars(envvars map[string]string, includes, excludes []string) (map[string]string, error) {
+	matchAny := func(key string, patterns []string) (bool, error) {
+		for _, pattern := range patterns {
+			ok, err := filepath.Match(pattern, key)
+			if err != nil {
+				return false, fmt.Errorf("invalid pattern '%s': %s", pattern, err)
+			}
+			if ok {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	}
+
+	filtered := make(map[string]string, len(envvars))
+
+	for key, value := range envvars {
+		if len(includes) > 0 {
+			included, err := matchAny(key, includes)
+			if err != nil {
+				return nil, err
+			}
+			if !included {
+				continue
+			}
+		}
+
+		excluded, err := matchAny(key, excludes)
+		if err != nil {
+			return nil, err
+		}
+		if excluded {
+			continue
+		}
+
+		filtered[key] = value
+	}
+
+	return filtered, nil
+}
@@ -0,0 +1,133 @@
+package aws
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+type (
+	// EnvvarSet has env vars and secrets (as their source) of a container in a
+	// revision of an environment.
+	EnvvarSet struct {
+		Label   string
+		Envvars map[string]string
+		Secrets map[string]string
+	}
+)
+
+// GetEnvvarSet reads env vars of container, label identifies where they came
+// from, e.g. "production revision[42]".
+func (sess *AWSSession) GetEnvvarSet(service string, revision int64, container string) EnvvarSet {
+	taskDefinition := DescribeTaskDefinition(sess.Client, service, revision)
+	def := GetContainerDefinition(taskDefinition, container)
+
+	label := fmt.Sprintf("%s revision[%d]", sess.Environment.ClusterName, *taskDefinition.Revision)
+	if len(taskDefinition.ContainerDefinitions) > 1 {
+		label += fmt.Sprintf(" container[%s]", *def.Name)
+	}
+
+	return EnvvarSet{
+		Label:   label,
+		Envvars: envvarsToMap(def.Environment),
+		Secrets: secretsToMap(def.Secrets),
+	}
+}
+
+// compareValue returns the value used to compare key, a plain var is always
+// different of a secret.
+func (set EnvvarSet) compareValue(key string) (string, bool) {
+	if source, ok := set.Secrets[key]; ok {
+		return "secret:" + source, true
+	}
+	if value, ok := set.Envvars[key]; ok {
+		return "value:" + value, true
+	}
+
+	return "", false
+}
+
+func (set EnvvarSet) displayValue(key string, masker *Masker) string {
+	if source, ok := set.Secrets[key]; ok {
+		return "secret from " + source
+	}
+
+	return formatPropertyValue(masker.Mask(key, set.Envvars[key]))
+}
+
+// DiffEnvvarSets shows keys missing in to, extra in to and with different
+// values, sensitive values are masked unless reveal is true. It returns false
+// when both have the same env vars.
+func (sess *AWSSession) DiffEnvvarSets(from, to EnvvarSet, reveal bool) bool {
+	masker := sess.masker(reveal)
+
+	keys := make([]string, 0, len(from.Envvars)+len(from.Secrets))
+	seen := make(map[string]struct{})
+
+	for _, set := range []EnvvarSet{from, to} {
+		for _, m := range []map[string]string{set.Envvars, set.Secrets} {
+			for key := range m {
+				if _, ok := seen[key]; !ok {
+					seen[key] = struct{}{}
+					keys = append(keys, key)
+				}
+			}
+		}
+	}
+	sort.Strings(keys)
+
+	var missing, extra, different []FieldDiff
+
+	for _, key := range keys {
+		fromValue, inFrom := from.compareValue(key)
+		toValue, inTo := to.compareValue(key)
+
+		switch {
+		case !inTo:
+			missing = append(missing, FieldDiff{
+				Field:  key,
+				Status: DiffRemoved,
+				Before: from.displayValue(key, masker),
+			})
+		case !inFrom:
+			extra = append(extra, FieldDiff{
+				Field:  key,
+				Status: DiffAdded,
+				After:  to.displayValue(key, masker),
+			})
+		case fromValue != toValue:
+			different = append(different, FieldDiff{
+				Field:  key,
+				Status: DiffChanged,
+				Before: from.displayValue(key, masker),
+				After:  to.displayValue(key, masker),
+			})
+		}
+	}
+
+	fmt.Printf("# Comparing env vars of %s -> %s:\n", from.Label, to.Label)
+
+	if len(missing) == 0 && len(extra) == 0 && len(different) == 0 {
+		fmt.Println("No differences")
+		return false
+	}
+
+	useColor := terminal.IsTerminal(int(os.Stdout.Fd()))
+
+	if len(missing) > 0 {
+		fmt.Printf("Missing in %s:\n", to.Label)
+		printFieldDiffs(missing, useColor)
+	}
+	if len(extra) > 0 {
+		fmt.Printf("Extra in %s:\n", to.Label)
+		printFieldDiffs(extra, useColor)
+	}
+	if len(different) > 0 {
+		fmt.Println("Different values:")
+		printFieldDiffs(different, useColor)
+	}
+
+	return true
+}
@@ -0,0 +1,136 @@
+package aws
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+type envvarRevision struct {
+	revision int64
+	output   *ecs.DescribeTaskDefinitionOutput
+	set      EnvvarSet
+}
+
+// findContainerDefinition returns container by name, or nil when it doesn't
+// exist in this revision.
+func findContainerDefinition(taskDefinition *ecs.TaskDefinition, container string) *ecs.ContainerDefinition {
+	for _, containerDefinition := range taskDefinition.ContainerDefinitions {
+		if *containerDefinition.Name == container {
+			return containerDefinition
+		}
+	}
+
+	return nil
+}
+
+// listEnvvarRevisions returns env vars of container in the last revisions of
+// service, oldest first.
+func (sess *AWSSession) listEnvvarRevisions(service, container string, limit int64, showInactive bool) []envvarRevision {
+	arns := sess.listRevisionArns(service, limit, showInactive)
+	if len(arns) == 0 {
+		fmt.Println("No revision was found to this service!")
+		os.Exit(1)
+	}
+
+	outputs, err := DescribeTaskDefinitions(sess.Client, arns)
+	checkErr("DescribeTaskDefinition", err)
+
+	// Container is chosen by the newest revision, older ones may not have it
+	container = *GetContainerDefinition(outputs[arns[0]].TaskDefinition, container).Name
+
+	revisions := make([]envvarRevision, 0, len(arns))
+
+	for k := len(arns) - 1; k >= 0; k-- {
+		output := outputs[arns[k]]
+
+		rev := envvarRevision{
+			revision: *output.TaskDefinition.Revision,
+			output:   output,
+		}
+
+		if def := findContainerDefinition(output.TaskDefinition, container); def != nil {
+			rev.set = EnvvarSet{
+				Envvars: envvarsToMap(def.Environment),
+				Secrets: secretsToMap(def.Secrets),
+			}
+		}
+
+		revisions = append(revisions, rev)
+	}
+
+	return revisions
+}
+
+// EnvvarHistory shows each revision where key was added, changed or removed,
+// with who deployed it when the deploy journal has it. Sensitive values are
+// masked unless reveal is true.
+func (sess *AWSSession) EnvvarHistory(service, container, key string, limit int64, showInactive, reveal bool) {
+	revisions := sess.listEnvvarRevisions(service, container, limit, showInactive)
+	masker := sess.masker(reveal)
+
+	fmt.Printf("# History of '%s' in '%s':\n", key, service)
+	fmt.Printf("%-8s   %-19s   %-40s   %s\n", "REVISION", "REGISTERED", "DEPLOYED BY", "VALUE")
+
+	var (
+		last    string
+		existed bool
+		changes int
+	)
+
+	for _, rev := range revisions {
+		// Compared before masking, so a change between masked values is shown
+		current, exists := rev.set.compareValue(key)
+		if exists == existed && current == last {
+			continue
+		}
+
+		value := "<removed>"
+		if exists {
+			value = rev.set.displayValue(key, masker)
+		}
+
+		fmt.Printf("%-8d   %-19s   %-40s   %s\n",
+			rev.revision,
+			formatRegisteredAt(rev.output.TaskDefinition),
+			GetJournalEntry(rev.output.Tags).String(),
+			value,
+		)
+
+		last, existed = current, exists
+		changes++
+	}
+
+	if changes == 0 {
+		fmt.Printf("'%s' was not found in the last %d revisions\n", key, len(revisions))
+	}
+}
+
+// EnvvarsHistory shows what changed in env vars between each revision,
+// sensitive values are masked unless reveal is true.
+func (sess *AWSSession) EnvvarsHistory(service, container string, limit int64, showInactive, reveal bool) {
+	revisions := sess.listEnvvarRevisions(service, container, limit, showInactive)
+	masker := sess.masker(reveal)
+	useColor := terminal.IsTerminal(int(os.Stdout.Fd()))
+
+	fmt.Printf("# History of env vars in '%s' since revision[%d]:\n", service, revisions[0].revision)
+
+	for k := 1; k < len(revisions); k++ {
+		before, after := revisions[k-1].set, revisions[k].set
+
+		diffs := compareEnvvars("", before.Envvars, after.Envvars, masker)
+		diffs = append(diffs, compareEnvvars("secret.", before.Secrets, after.Secrets, nil)...)
+		if len(diffs) == 0 {
+			continue
+		}
+
+		fmt.Printf("revision[%d] registered at %s, deployed by %s:\n",
+			revisions[k].revision,
+			formatRegisteredAt(revisions[k].output.TaskDefinition),
+			GetJournalEntry(revisions[k].output.Tags).String(),
+		)
+		printFieldDiffs(diffs, useColor)
+	}
+}
@@ -0,0 +1,184 @@
+package aws
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	KeyCaseKeep  = "keep"
+	KeyCaseUpper = "upper"
+	KeyCaseLower = "lower"
+
+	ArrayJoinComma = "comma"
+	ArrayJoinSpace = "space"
+	ArrayJoinJSON  = "json"
+	ArrayJoinIndex = "index"
+)
+
+type (
+	// EnvFileOptions tells how env vars are read from stdin.
+	EnvFileOptions struct {
+		// Json reads a JSON object instead of a .env file
+		Json bool
+		// Interpolate replaces ${VAR} in .env files
+		Interpolate bool
+		// Separator joins keys of nested objects, e.g. PARENT_CHILD
+		Separator string
+		// Case of keys: keep, upper or lower
+		Case string
+		// ArrayJoin converts arrays: comma, space, json or index (KEY_0, KEY_1),
+		// arrays are rejected when it's empty
+		ArrayJoin string
+	}
+)
+
+func (options EnvFileOptions) validate() error {
+	switch options.Case {
+	case "", KeyCaseKeep, KeyCaseUpper, KeyCaseLower:
+	default:
+		return fmt.Errorf("invalid key case '%s', use: %s, %s or %s", options.Case, KeyCaseKeep, KeyCaseUpper, KeyCaseLower)
+	}
+
+	switch options.ArrayJoin {
+	case "", ArrayJoinComma, ArrayJoinSpace, ArrayJoinJSON, ArrayJoinIndex:
+	default:
+		return fmt.Errorf("invalid array join '%s', use: %s, %s, %s or %s", options.ArrayJoin, ArrayJoinComma, ArrayJoinSpace, ArrayJoinJSON, ArrayJoinIndex)
+	}
+
+	return nil
+}
+
+func (options EnvFileOptions) key(parent, key string) string {
+	if !strings.EqualFold("", parent) {
+		key = parent + options.Separator + key
+	}
+
+	switch options.Case {
+	case KeyCaseUpper:
+		return strings.ToUpper(key)
+	case KeyCaseLower:
+		return strings.ToLower(key)
+	}
+
+	return key
+}
+
+// ParseJSONEnvvars reads a JSON object as env vars. Nested objects are
+// flattened, numbers are kept as written and null becomes an empty value.
+func ParseJSONEnvvars(reader io.Reader, options EnvFileOptions) (map[string]string, error) {
+	if strings.EqualFold("", options.Separator) {
+		options.Separator = "_"
+	}
+
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+
+	content, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+
+	var object map[string]interface{}
+
+	if err := decoder.Decode(&object); err != nil {
+		return nil, err
+	}
+
+	envvars := make(map[string]string)
+	if err := options.flatten(envvars, "", object); err != nil {
+		return nil, err
+	}
+
+	return envvars, nil
+}
+
+func (options EnvFileOptions) flatten(envvars map[string]string, parent string, object map[string]interface{}) error {
+	// Sorted, so a conflict is reported always in the same way
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if err := options.set(envvars, options.key(parent, key), object[key]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (options EnvFileOptions) set(envvars map[string]string, key string, value interface{}) error {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return options.flatten(envvars, key, v)
+	case []interface{}:
+		return options.setArray(envvars, key, v)
+	}
+
+	if _, ok := envvars[key]; ok {
+		return fmt.Errorf("key '%s' is defined more than once after flattening", key)
+	}
+
+	envvars[key] = formatJSONScalar(value)
+	return nil
+}
+
+func (options EnvFileOptions) setArray(envvars map[string]string, key string, array []interface{}) error {
+	switch options.ArrayJoin {
+	case "":
+		return fmt.Errorf("key '%s' is an array, use --json-array with %s, %s, %s or %s to convert it", key, ArrayJoinComma, ArrayJoinSpace, ArrayJoinJSON, ArrayJoinIndex)
+	case ArrayJoinJSON:
+		content, _ := json.Marshal(array)
+		return options.set(envvars, key, string(content))
+	case ArrayJoinIndex:
+		for k, item := range array {
+			if err := options.set(envvars, options.key(key, strconv.Itoa(k)), item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	items := make([]string, len(array))
+	for k, item := range array {
+		switch item.(type) {
+		case map[string]interface{}, []interface{}:
+			return fmt.Errorf("key '%s' has nested objects or arrays, use --json-array %s or %s", key, ArrayJoinJSON, ArrayJoinIndex)
+		}
+
+		items[k] = formatJSONScalar(item)
+	}
+
+	sep := ","
+	if options.ArrayJoin == ArrayJoinSpace {
+		sep = " "
+	}
+
+	return options.set(envvars, key, strings.Join(items, sep))
+}
+
+func formatJSONScalar(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case json.Number:
+		return v.String()
+	case string:
+		return v
+	}
+
+	return fmt.Sprint(value)
+}
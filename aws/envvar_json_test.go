@@ -0,0 +1,128 @@
+package aws
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseJSONEnvvars(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		options EnvFileOptions
+		want    map[string]string
+	}{
+		{
+			name:    "scalars",
+			content: `{"S": "text", "I": 10, "F": 1.50, "B": true, "N": null, "BIG": 12345678901234567890}`,
+			want:    map[string]string{"S": "text", "I": "10", "F": "1.50", "B": "true", "N": "", "BIG": "12345678901234567890"},
+		},
+		{
+			name:    "nested objects use the default separator",
+			content: `{"db": {"host": "localhost", "port": 5432}}`,
+			want:    map[string]string{"db_host": "localhost", "db_port": "5432"},
+		},
+		{
+			name:    "separator and upper case",
+			content: `{"db": {"host": "localhost", "replica": {"host": "replica"}}}`,
+			options: EnvFileOptions{Separator: "__", Case: KeyCaseUpper},
+			want:    map[string]string{"DB__HOST": "localhost", "DB__REPLICA__HOST": "replica"},
+		},
+		{
+			name:    "lower case",
+			content: `{"Log": {"Level": "debug"}}`,
+			options: EnvFileOptions{Case: KeyCaseLower},
+			want:    map[string]string{"log_level": "debug"},
+		},
+		{
+			name:    "array comma",
+			content: `{"HOSTS": ["a", "b", 3]}`,
+			options: EnvFileOptions{ArrayJoin: ArrayJoinComma},
+			want:    map[string]string{"HOSTS": "a,b,3"},
+		},
+		{
+			name:    "array space",
+			content: `{"HOSTS": ["a", "b"]}`,
+			options: EnvFileOptions{ArrayJoin: ArrayJoinSpace},
+			want:    map[string]string{"HOSTS": "a b"},
+		},
+		{
+			name:    "array json",
+			content: `{"HOSTS": ["a", {"b": 1}]}`,
+			options: EnvFileOptions{ArrayJoin: ArrayJoinJSON},
+			want:    map[string]string{"HOSTS": `["a",{"b":1}]`},
+		},
+		{
+			name:    "array index",
+			content: `{"HOSTS": ["a", {"port": 1}]}`,
+			options: EnvFileOptions{ArrayJoin: ArrayJoinIndex, Case: KeyCaseUpper},
+			want:    map[string]string{"HOSTS_0": "a", "HOSTS_1_PORT": "1"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseJSONEnvvars(strings.NewReader(test.content), test.options)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseJSONEnvvarsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		options EnvFileOptions
+		err     string
+	}{
+		{
+			name:    "array without mode",
+			content: `{"HOSTS": ["a"]}`,
+			err:     "key 'HOSTS' is an array",
+		},
+		{
+			name:    "nested array with comma",
+			content: `{"HOSTS": [["a"]]}`,
+			options: EnvFileOptions{ArrayJoin: ArrayJoinComma},
+			err:     "key 'HOSTS' has nested objects or arrays",
+		},
+		{
+			name:    "conflict after flattening",
+			content: `{"a": {"b": 1}, "A_B": 2}`,
+			options: EnvFileOptions{Case: KeyCaseUpper},
+			err:     "key 'A_B' is defined more than once",
+		},
+		{
+			name:    "invalid case",
+			content: `{}`,
+			options: EnvFileOptions{Case: "camel"},
+			err:     "invalid key case 'camel'",
+		},
+		{
+			name:    "invalid array join",
+			content: `{}`,
+			options: EnvFileOptions{ArrayJoin: "tab"},
+			err:     "invalid array join 'tab'",
+		},
+		{
+			name:    "not an object",
+			content: `["a"]`,
+			err:     "cannot unmarshal array",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseJSONEnvvars(strings.NewReader(test.content), test.options)
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("got %v, want error containing %q", err, test.err)
+			}
+		})
+	}
+}
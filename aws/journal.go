@@ -0,0 +1,96 @@
+package aws
+
+import (
+	"fmt"
+	"os/user"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+// Deploy journal is kept as tags of each revision, so everyone sees who was
+// the last one deploying it.
+const (
+	deployedByTag = "deploy-ecs:deployed-by"
+	deployedAtTag = "deploy-ecs:deployed-at"
+	deployedToTag = "deploy-ecs:deployed-to"
+)
+
+type (
+	// JournalEntry is the last deploy of a revision.
+	JournalEntry struct {
+		DeployedBy string
+		DeployedAt time.Time
+		DeployedTo string
+	}
+)
+
+// getDeployer returns the AWS identity (e.g. user/alice), or the local user
+// when it's not available.
+func (sess *AWSSession) getDeployer() string {
+	svc := sts.New(sess.Client)
+
+	resp, err := svc.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err == nil {
+		arn := *resp.Arn
+		return arn[strings.LastIndex(arn, ":")+1:]
+	}
+
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+
+	return "unknown"
+}
+
+// recordDeploy writes the journal entry of a deploy, it's not an error when
+// the revision cannot be tagged.
+func (sess *AWSSession) recordDeploy(taskDefinitionArn string) {
+	svc := ecs.New(sess.Client)
+
+	_, err := svc.TagResource(&ecs.TagResourceInput{
+		ResourceArn: aws.String(taskDefinitionArn),
+		Tags: []*ecs.Tag{
+			{Key: aws.String(deployedByTag), Value: aws.String(sess.getDeployer())},
+			{Key: aws.String(deployedAtTag), Value: aws.String(time.Now().UTC().Format(time.RFC3339))},
+			{Key: aws.String(deployedToTag), Value: aws.String(sess.Environment.ClusterName)},
+		},
+	})
+	if err != nil {
+		fmt.Println("# Cannot record deploy in the journal:", err)
+	}
+}
+
+// GetJournalEntry returns the last deploy recorded in tags, nil when the
+// revision has never been deployed by this tool.
+func GetJournalEntry(tags []*ecs.Tag) *JournalEntry {
+	var entry JournalEntry
+
+	for _, tag := range tags {
+		switch aws.StringValue(tag.Key) {
+		case deployedByTag:
+			entry.DeployedBy = aws.StringValue(tag.Value)
+		case deployedAtTag:
+			entry.DeployedAt, _ = time.Parse(time.RFC3339, aws.StringValue(tag.Value))
+		case deployedToTag:
+			entry.DeployedTo = aws.StringValue(tag.Value)
+		}
+	}
+
+	if strings.EqualFold("", entry.DeployedBy) {
+		return nil
+	}
+
+	return &entry
+}
+
+func (entry *JournalEntry) String() string {
+	if entry == nil {
+		return "-"
+	}
+
+	return fmt.Sprintf("%s on %s at %s", entry.DeployedBy, entry.DeployedTo, entry.DeployedAt.Local().Format("2006-01-02 15:04"))
+}
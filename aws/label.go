@@ -0,0 +1,317 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi"
+)
+
+// labelTagPrefix is followed by the label name on the tag key, so one revision
+// can have several labels.
+const labelTagPrefix = "deploy-ecs:label:"
+
+var labelRegexp = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_.-]*$`)
+
+type (
+	// LabelStore keeps labels which cannot be tagged, the key is
+	// "<region>/<family>" and the value maps label to revision.
+	LabelStore map[string]map[string]int64
+
+	// Label is a label pointing to a revision, tagged or stored locally.
+	Label struct {
+		Name     string
+		Revision int64
+		Arn      string
+		Local    bool
+	}
+)
+
+func getLabelStoreFilename() string {
+	user, _ := user.Current()
+	return filepath.Join(user.HomeDir, ".deploy-ecs-labels")
+}
+
+func loadLabelStore() LabelStore {
+	store := make(LabelStore)
+
+	content, err := ioutil.ReadFile(getLabelStoreFilename())
+	if err != nil {
+		return store
+	}
+
+	json.Unmarshal(content, &store)
+	return store
+}
+
+func (store LabelStore) save() {
+	content, _ := json.MarshalIndent(store, "", "  ")
+
+	err := ioutil.WriteFile(getLabelStoreFilename(), content, 0644)
+	if err != nil {
+		fmt.Println("Cannot save labels:", err)
+		os.Exit(1)
+	}
+}
+
+func (sess *AWSSession) labelStoreKey(service string) string {
+	return sess.Environment.Region + "/" + service
+}
+
+func ValidateLabel(label string) error {
+	if !labelRegexp.MatchString(label) {
+		return fmt.Errorf("Invalid label '%s', it needs to start with a letter and have only letters, numbers, '_', '.' or '-'", label)
+	}
+
+	return nil
+}
+
+// listLabelTagKeys returns tag keys of labels used in the region, there are
+// far less keys than task definitions.
+func (sess *AWSSession) listLabelTagKeys() ([]string, error) {
+	svc := resourcegroupstaggingapi.New(sess.Client)
+
+	keys := make([]string, 0)
+
+	err := svc.GetTagKeysPages(&resourcegroupstaggingapi.GetTagKeysInput{}, func(resp *resourcegroupstaggingapi.GetTagKeysOutput, lastPage bool) bool {
+		for _, key := range resp.TagKeys {
+			if strings.HasPrefix(*key, labelTagPrefix) {
+				keys = append(keys, *key)
+			}
+		}
+		return true
+	})
+
+	return keys, err
+}
+
+// listTaggedLabels returns labels tagged on revisions of service, when label
+// is informed only that one is searched. Revisions are filtered by tag on
+// the server, one call to each label.
+func (sess *AWSSession) listTaggedLabels(service, label string) ([]Label, error) {
+	keys := []string{labelTagPrefix + label}
+	if strings.EqualFold("", label) {
+		var err error
+
+		keys, err = sess.listLabelTagKeys()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	svc := resourcegroupstaggingapi.New(sess.Client)
+
+	labels := make([]Label, 0)
+
+	for _, key := range keys {
+		params := &resourcegroupstaggingapi.GetResourcesInput{
+			ResourceTypeFilters: aws.StringSlice([]string{"ecs:task-definition"}),
+			TagFilters: []*resourcegroupstaggingapi.TagFilter{{
+				Key: aws.String(key),
+			}},
+		}
+
+		err := svc.GetResourcesPages(params, func(resp *resourcegroupstaggingapi.GetResourcesOutput, lastPage bool) bool {
+			for _, resource := range resp.ResourceTagMappingList {
+				arn := *resource.ResourceARN
+				if getFamilyFromTaskDefinition(arn) != service {
+					continue
+				}
+
+				labels = append(labels, Label{
+					Name:     strings.TrimPrefix(key, labelTagPrefix),
+					Revision: getRevisionNumber(arn),
+					Arn:      arn,
+				})
+			}
+			return true
+		})
+		if err != nil {
+			return labels, err
+		}
+	}
+
+	return labels, nil
+}
+
+// ListLabels returns labels of service, tagged ones first. Local labels are
+// used when tags cannot be read.
+func (sess *AWSSession) ListLabels(service string) []Label {
+	return sess.findLabels(service, "")
+}
+
+func (sess *AWSSession) findLabels(service, label string) []Label {
+	labels, err := sess.listTaggedLabels(service, label)
+	if err != nil {
+		fmt.Println("# Cannot read labels from tags, using only local ones:", err)
+	}
+
+	for name, revision := range loadLabelStore()[sess.labelStoreKey(service)] {
+		if !strings.EqualFold("", label) && name != label {
+			continue
+		}
+
+		labels = append(labels, Label{
+			Name:     name,
+			Revision: revision,
+			Local:    true,
+		})
+	}
+
+	return labels
+}
+
+// GetLabel returns which revision label points to, nil when it doesn't exist.
+func (sess *AWSSession) GetLabel(service, label string) *Label {
+	labels := sess.findLabels(service, label)
+	if len(labels) == 0 {
+		return nil
+	}
+
+	return &labels[0]
+}
+
+func (sess *AWSSession) PrintLabels(service string) {
+	labels := sess.ListLabels(service)
+	if len(labels) == 0 {
+		fmt.Printf("No labels were found to '%s'\n", service)
+		return
+	}
+
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].Name < labels[j].Name
+	})
+
+	fmt.Printf("%-20s   %-8s   %s\n", "LABEL", "REVISION", "STORED ON")
+	for _, label := range labels {
+		storedOn := "tag"
+		if label.Local {
+			storedOn = "local"
+		}
+
+		fmt.Printf("%-20s   %-8d   %s\n", label.Name, label.Revision, storedOn)
+	}
+}
+
+func (sess *AWSSession) tagLabel(arn, label string) error {
+	svc := ecs.New(sess.Client)
+
+	_, err := svc.TagResource(&ecs.TagResourceInput{
+		ResourceArn: aws.String(arn),
+		Tags: []*ecs.Tag{{
+			Key:   aws.String(labelTagPrefix + label),
+			Value: aws.String(label),
+		}},
+	})
+
+	return err
+}
+
+func (sess *AWSSession) untagLabel(arn, label string) {
+	svc := ecs.New(sess.Client)
+
+	_, err := svc.UntagResource(&ecs.UntagResourceInput{
+		ResourceArn: aws.String(arn),
+		TagKeys:     aws.StringSlice([]string{labelTagPrefix + label}),
+	})
+	checkErr("UntagResource", err)
+}
+
+// SetLabel makes label point to revision, if label already exists move needs
+// to be true. Labels are tagged, or stored locally when tagging fails.
+func (sess *AWSSession) SetLabel(service, label string, revision int64, move bool) {
+	if err := ValidateLabel(label); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	current := sess.GetLabel(service, label)
+	if current != nil && !move {
+		fmt.Printf("Label '%s' already points to revision[%d], use move to change it\n", label, current.Revision)
+		os.Exit(1)
+	}
+	if current == nil && move {
+		fmt.Printf("Label '%s' was not found to '%s'\n", label, service)
+		os.Exit(1)
+	}
+	if current != nil && current.Revision == revision {
+		fmt.Printf("Label '%s' already points to revision[%d]\n", label, revision)
+		return
+	}
+
+	arn := sess.GetTaskDefinitionArn(service, revision)
+	store := loadLabelStore()
+	key := sess.labelStoreKey(service)
+
+	err := sess.tagLabel(arn, label)
+	if err != nil {
+		fmt.Printf("# Cannot tag revision, label will be stored locally: %s\n", err)
+
+		if _, ok := store[key]; !ok {
+			store[key] = make(map[string]int64)
+		}
+		store[key][label] = revision
+	} else if _, ok := store[key][label]; ok {
+		delete(store[key], label)
+	}
+
+	if current != nil && !current.Local {
+		sess.untagLabel(current.Arn, label)
+	}
+
+	store.save()
+
+	fmt.Printf("Label '%s' of '%s' points to revision[%d]\n", label, service, revision)
+}
+
+func (sess *AWSSession) DeleteLabel(service, label string) {
+	current := sess.GetLabel(service, label)
+	if current == nil {
+		fmt.Printf("Label '%s' was not found to '%s'\n", label, service)
+		os.Exit(1)
+	}
+
+	if current.Local {
+		store := loadLabelStore()
+		delete(store[sess.labelStoreKey(service)], label)
+		store.save()
+	} else {
+		sess.untagLabel(current.Arn, label)
+	}
+
+	fmt.Printf("Label '%s' was removed from revision[%d]\n", label, current.Revision)
+}
+
+// ResolveTaskDefinitionArn returns the ARN of revisionOrLabel, which can be a
+// revision number or a label. Empty means the last revision.
+func (sess *AWSSession) ResolveTaskDefinitionArn(service, revisionOrLabel string) string {
+	if strings.EqualFold("", revisionOrLabel) {
+		return sess.GetTaskDefinitionArn(service, 0)
+	}
+
+	if revision, err := strconv.ParseInt(revisionOrLabel, 10, 64); err == nil {
+		return sess.GetTaskDefinitionArn(service, revision)
+	}
+
+	label := sess.GetLabel(service, revisionOrLabel)
+	if label == nil {
+		fmt.Printf("Label '%s' was not found to '%s'\n", revisionOrLabel, service)
+		os.Exit(1)
+	}
+
+	if strings.EqualFold("", label.Arn) {
+		return sess.GetTaskDefinitionArn(service, label.Revision)
+	}
+
+	return label.Arn
+}
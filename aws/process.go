@@ -1,8 +1,10 @@
 package aws
 
 import (
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/NeowayLabs/logger"
@@ -12,6 +14,151 @@ import (
 	"github.com/guilherme-santos/deploy-ecs/ssh"
 )
 
+// maxConcurrentInspections limits how many tasks are inspected at the same
+// time, it stays below the default MaxSessions of sshd since tasks on the same
+// instance share one connection.
+const maxConcurrentInspections = 8
+
+type processStatus struct {
+	taskID        string
+	revision      string
+	uptime        string
+	stoppedReason string
+	entry         CacheEntry
+	hostErr       error
+	containersErr error
+}
+
+func newProcessStatus(task *ecs.Task) *processStatus {
+	taskArn := *task.TaskArn
+
+	process := &processStatus{
+		taskID:   taskArn[strings.LastIndex(taskArn, "/")+1:],
+		revision: getRevisionFromTaskDefinition(*task.TaskDefinitionArn),
+	}
+
+	status := *task.LastStatus
+
+	if strings.EqualFold("RUNNING", status) {
+		if task.StartedAt != nil {
+			startedAt := *task.StartedAt
+			process.uptime = formatUptime(time.Since(startedAt))
+		} else {
+			process.uptime = "PENDING"
+		}
+	} else if strings.EqualFold("STOPPED", status) {
+		process.uptime = status
+		process.stoppedReason = aws.StringValue(task.StoppedReason)
+	} else {
+		process.uptime = status
+	}
+
+	return process
+}
+
+// loadCache reads the cached entry of process, it's ignored when the task is
+// running on another container instance than the cached one.
+func (process *processStatus) loadCache(env *deploy.Environment, task *ecs.Task) {
+	containerInstanceArn := aws.StringValue(task.ContainerInstanceArn)
+
+	process.entry = GetTaskFromCache(env, process.taskID)
+	if process.entry.ContainerInstanceArn != containerInstanceArn {
+		process.entry = CacheEntry{}
+	}
+
+	process.entry.TaskArn = *task.TaskArn
+	process.entry.ContainerInstanceArn = containerInstanceArn
+}
+
+// resolveRemoteHosts finds the public DNS of every task not cached yet with
+// one batch of calls to ECS and EC2.
+func (sess *AWSSession) resolveRemoteHosts(processes []*processStatus) {
+	containerInstanceArns := make([]string, 0, len(processes))
+	seen := make(map[string]struct{}, len(processes))
+
+	for _, process := range processes {
+		arn := process.entry.ContainerInstanceArn
+		if process.entry.HasRemoteHost() {
+			continue
+		}
+		if strings.EqualFold("", arn) {
+			process.hostErr = errors.New("task is not running on a container instance")
+			continue
+		}
+		if _, ok := seen[arn]; ok {
+			continue
+		}
+
+		seen[arn] = struct{}{}
+		containerInstanceArns = append(containerInstanceArns, arn)
+	}
+
+	if len(containerInstanceArns) == 0 {
+		return
+	}
+
+	instances, err := DescribeInstancesOfContainerInstances(sess.Client, sess.Environment.ClusterName, containerInstanceArns)
+
+	for _, process := range processes {
+		if process.entry.HasRemoteHost() || process.hostErr != nil {
+			continue
+		}
+
+		if err != nil {
+			process.hostErr = err
+			continue
+		}
+
+		instance, ok := instances[process.entry.ContainerInstanceArn]
+		if !ok {
+			process.hostErr = errors.New("no instaces was found")
+			continue
+		}
+
+		process.entry.RemoteHost = aws.StringValue(instance.PublicDnsName)
+		SaveTaskToCache(sess.Environment, process.taskID, process.entry)
+	}
+}
+
+// resolveContainers asks the ECS agent of each instance which containers are
+// running, up to maxConcurrentInspections tasks at the same time.
+func (sess *AWSSession) resolveContainers(processes []*processStatus) {
+	var wg sync.WaitGroup
+
+	queue := make(chan *processStatus)
+
+	for i := 0; i < maxConcurrentInspections; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for process := range queue {
+				containers, err := ssh.GetContainers(sess.Environment, process.entry.RemoteHost, process.entry.TaskArn)
+				if err != nil {
+					process.containersErr = err
+					InvalidateTaskCache(sess.Environment, process.taskID)
+					continue
+				}
+
+				process.entry.Containers = containers
+				SaveTaskToCache(sess.Environment, process.taskID, process.entry)
+			}
+		}()
+	}
+
+	for _, process := range processes {
+		if process.hostErr != nil || process.entry.HasContainer() {
+			continue
+		}
+
+		queue <- process
+	}
+
+	close(queue)
+	wg.Wait()
+}
+
 func (sess *AWSSession) ListProcess(services []string, showAll bool) {
 	tasks := make([]*ecs.Task, 0)
 
@@ -25,85 +172,65 @@ func (sess *AWSSession) ListProcess(services []string, showAll bool) {
 		return
 	}
 
-	fmt.Println("TASK ID                                  REVISION   UPTIME     PUBLIC DNS")
+	processes := make([]*processStatus, len(tasks))
+	for k, task := range tasks {
+		processes[k] = newProcessStatus(task)
+		processes[k].loadCache(sess.Environment, task)
+	}
 
-	for _, task := range tasks {
-		taskDefinitionArn := *task.TaskDefinitionArn
-		taskArn := *task.TaskArn
-		taskID := taskArn[strings.LastIndex(taskArn, "/")+1:]
-		taskRevision := getRevisionFromTaskDefinition(taskDefinitionArn)
-		status := *task.LastStatus
-
-		var (
-			uptime        string
-			stoppedReason string
-		)
-
-		if strings.EqualFold("RUNNING", status) {
-			if task.StartedAt != nil {
-				startedAt := *task.StartedAt
-				uptime = formatUptime(time.Since(startedAt))
-			} else {
-				uptime = "PENDING"
-			}
-		} else if strings.EqualFold("STOPPED", status) {
-			uptime = status
-			stoppedReason = *task.StoppedReason
-		} else {
-			uptime = status
-		}
+	sess.resolveRemoteHosts(processes)
+	sess.resolveContainers(processes)
 
-		entry := GetTaskFromCache(taskID)
-		if !entry.HasRemoteHost() {
-			instance, err := DescribeContainerInstances(sess.Client, sess.Environment.ClusterName, *task.ContainerInstanceArn)
-			if err != nil {
-				fmt.Printf("%-38s   %-8s   %-8s   Error: %s\n", taskID, taskRevision, uptime, err)
-				continue
-			}
+	fmt.Println("TASK ID                                  REVISION   UPTIME     PUBLIC DNS")
 
-			entry.TaskArn = taskArn
-			entry.ContainerInstanceArn = *task.ContainerInstanceArn
-			entry.RemoteHost = *instance.PublicDnsName
-			SaveTaskToCache(taskID, entry)
+	for _, process := range processes {
+		if process.hostErr != nil {
+			fmt.Printf("%-38s   %-8s   %-8s   Error: %s\n", process.taskID, process.revision, process.uptime, process.hostErr)
+			continue
 		}
 
-		fmt.Printf("%-38s   %-8s   %-8s   %s\n", taskID, taskRevision, uptime, entry.RemoteHost)
-		if !entry.HasContainer() {
-			containers, err := ssh.GetContainers(sess.Environment, entry.RemoteHost, entry.TaskArn)
-			if err != nil {
-				fmt.Println("  - Error getting containers:", err)
-				continue
-			}
-			if len(containers) == 0 {
-				fmt.Println("  - No container was found")
-				continue
-			}
-
-			entry.Containers = containers
-			SaveTaskToCache(taskID, entry)
+		fmt.Printf("%-38s   %-8s   %-8s   %s\n", process.taskID, process.revision, process.uptime, process.entry.RemoteHost)
+		if process.containersErr != nil {
+			fmt.Println("  - Error getting containers:", process.containersErr)
+			continue
+		}
+		if len(process.entry.Containers) == 0 {
+			fmt.Println("  - No container was found")
+			continue
 		}
 
 		fmt.Printf("  CONTAINER NAME                         CONTAINER ID")
-		if !strings.EqualFold("", stoppedReason) {
+		if !strings.EqualFold("", process.stoppedReason) {
 			fmt.Printf("   STOPPED REASON")
 		}
 		fmt.Println("")
 
-		for _, container := range entry.Containers {
+		for _, container := range process.entry.Containers {
 			fmt.Printf("    %-34s   %s", container.Name, container.DockerID[:12])
-			fmt.Print("   ", stoppedReason)
+			fmt.Print("   ", process.stoppedReason)
 			fmt.Println("")
 		}
 	}
 }
 
-func (sess *AWSSession) GetLogs(taskID, nameOrContainerID, tail string, follow bool) {
-	entry := GetTaskFromCache(taskID)
+// findTask returns where taskID is running. A cached host is looked up again
+// when it cannot be reached anymore, e.g. its instance was replaced, and the
+// cache is removed when containers of the task cannot be found.
+func (sess *AWSSession) findTask(taskID string) (CacheEntry, bool) {
+	entry := GetTaskFromCache(sess.Environment, taskID)
+	if entry.HasRemoteHost() {
+		if err := ssh.TryConnect(sess.Environment, entry.RemoteHost); err != nil {
+			fmt.Printf("# Cannot reach cached host '%s' of task %s, looking it up again\n", entry.RemoteHost, taskID)
+			InvalidateTaskCache(sess.Environment, taskID)
+			entry = CacheEntry{}
+		}
+	}
+
 	if !entry.HasRemoteHost() {
 		tasks := DescribeTasks(sess.Client, sess.Environment.ClusterName, []string{taskID})
 		if len(tasks) == 0 {
 			logger.Warn("No task was found with task id: %s", taskID)
-			return
+			return entry, false
 		}
 
 		task := tasks[0]
@@ -114,21 +241,32 @@ func (sess *AWSSession) GetLogs(taskID, nameOrContainerID, tail string, follow b
 		entry.TaskArn = *task.TaskArn
 		entry.ContainerInstanceArn = *task.ContainerInstanceArn
 		entry.RemoteHost = *instance.PublicDnsName
-		SaveTaskToCache(taskID, entry)
+		SaveTaskToCache(sess.Environment, taskID, entry)
 	}
+
 	if !entry.HasContainer() {
 		containers, err := ssh.GetContainers(sess.Environment, entry.RemoteHost, entry.TaskArn)
 		if err != nil {
+			InvalidateTaskCache(sess.Environment, taskID)
 			fmt.Println(err)
-			return
+			return entry, false
 		}
 		if len(containers) == 0 {
 			fmt.Println("No container was found")
-			return
+			return entry, false
 		}
 
 		entry.Containers = containers
-		SaveTaskToCache(taskID, entry)
+		SaveTaskToCache(sess.Environment, taskID, entry)
+	}
+
+	return entry, true
+}
+
+func (sess *AWSSession) GetLogs(taskID, nameOrContainerID, tail string, follow bool) {
+	entry, ok := sess.findTask(taskID)
+	if !ok {
+		return
 	}
 
 	var container deploy.Container
@@ -157,38 +295,9 @@ func (sess *AWSSession) GetLogs(taskID, nameOrContainerID, tail string, follow b
 }
 
 func (sess *AWSSession) Exec(taskID, nameOrContainerID, command string) {
-	entry := GetTaskFromCache(taskID)
-	if !entry.HasRemoteHost() {
-		tasks := DescribeTasks(sess.Client, sess.Environment.ClusterName, []string{taskID})
-		if len(tasks) == 0 {
-			logger.Warn("No task was found with task id: %s", taskID)
-			return
-		}
-
-		task := tasks[0]
-
-		instance, err := DescribeContainerInstances(sess.Client, sess.Environment.ClusterName, *task.ContainerInstanceArn)
-		checkErr("DescribeContainerInstances", err)
-
-		entry.TaskArn = *task.TaskArn
-		entry.ContainerInstanceArn = *task.ContainerInstanceArn
-		entry.RemoteHost = *instance.PublicDnsName
-		SaveTaskToCache(taskID, entry)
-	}
-
-	if !entry.HasContainer() {
-		containers, err := ssh.GetContainers(sess.Environment, entry.RemoteHost, entry.TaskArn)
-		if err != nil {
-			fmt.Println(err)
-			return
-		}
-		if len(containers) == 0 {
-			fmt.Println("No container was found")
-			return
-		}
-
-		entry.Containers = containers
-		SaveTaskToCache(taskID, entry)
+	entry, ok := sess.findTask(taskID)
+	if !ok {
+		return
 	}
 
 	var container deploy.Container
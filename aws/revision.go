@@ -0,0 +1,287 @@
+package aws
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// pinnedTag marks revisions that are never deregistered by prune.
+const pinnedTag = "deploy-ecs:pinned"
+
+func getRevisionNumber(arn string) int64 {
+	revision, _ := strconv.ParseInt(getRevisionFromTaskDefinition(arn), 10, 64)
+	return revision
+}
+
+// listRevisionArns returns the newest revisions of service first, including
+// the inactive ones when asked.
+func (sess *AWSSession) listRevisionArns(service string, limit int64, showInactive bool) []string {
+	arns := ListTaskDefinitions(sess.Client, service, limit)
+	if !showInactive {
+		return arns
+	}
+
+	arns = append(arns, ListTaskDefinitionsByStatus(sess.Client, service, ecs.TaskDefinitionStatusInactive, limit)...)
+	sort.Slice(arns, func(i, j int) bool {
+		return getRevisionNumber(arns[i]) > getRevisionNumber(arns[j])
+	})
+
+	if limit > 0 && int64(len(arns)) > limit {
+		arns = arns[:limit]
+	}
+
+	return arns
+}
+
+// GetRunningRevisions returns the task definition ARN each service running a
+// family started with service (as deploy does) is running on this cluster.
+// Old deployments still running are returned with "(draining)" after service
+// name.
+func (sess *AWSSession) GetRunningRevisions(service string) map[string][]string {
+	running := make(map[string][]string)
+	sess.addRunningRevisions(running, sess.Environment.ClusterName, "", sess.ListTaskDefinitionStartedWith(service))
+
+	return running
+}
+
+// getRunningRevisionsInRegion does the same as GetRunningRevisions on every
+// cluster of the region, since task definitions are shared by all of them.
+func (sess *AWSSession) getRunningRevisionsInRegion(service string) map[string][]string {
+	families := sess.ListTaskDefinitionStartedWith(service)
+	running := make(map[string][]string)
+
+	for _, cluster := range ListClusters(sess.Client) {
+		sess.addRunningRevisions(running, cluster, cluster[strings.LastIndex(cluster, "/")+1:]+"/", families)
+	}
+
+	return running
+}
+
+// addRunningRevisions matches the services of cluster by the family of their
+// task definitions, a service doesn't need to be named as its family.
+func (sess *AWSSession) addRunningRevisions(running map[string][]string, cluster, prefix string, families []string) {
+	if len(families) == 0 {
+		return
+	}
+
+	for _, svc := range DescribeServices(sess.Client, cluster, ListServices(sess.Client, cluster)) {
+		for _, deployment := range svc.Deployments {
+			arn := aws.StringValue(deployment.TaskDefinition)
+			if !containsString(families, getFamilyFromTaskDefinition(arn)) {
+				continue
+			}
+
+			name := prefix + *svc.ServiceName
+
+			switch aws.StringValue(deployment.Status) {
+			case "PRIMARY":
+			case "ACTIVE":
+				name += " (draining)"
+			default:
+				continue
+			}
+
+			running[arn] = append(running[arn], name)
+		}
+	}
+}
+
+func isPinned(tags []*ecs.Tag) bool {
+	for _, tag := range tags {
+		if aws.StringValue(tag.Key) == pinnedTag {
+			return true
+		}
+	}
+
+	return false
+}
+
+func formatRegisteredAt(taskDefinition *ecs.TaskDefinition) string {
+	if taskDefinition.RegisteredAt == nil {
+		return "-"
+	}
+
+	return taskDefinition.RegisteredAt.Local().Format("2006-01-02 15:04:05")
+}
+
+// ListRevisions shows the last revisions of service (limit zero means all),
+// with every container image and which services are running each of them.
+func (sess *AWSSession) ListRevisions(service string, limit int64, showInactive bool) {
+	arns := sess.listRevisionArns(service, limit, showInactive)
+	if len(arns) == 0 {
+		fmt.Println("No revision was found to this service!")
+		return
+	}
+
+	taskDefinitions, err := DescribeTaskDefinitions(sess.Client, arns)
+	checkErr("DescribeTaskDefinition", err)
+
+	running := sess.GetRunningRevisions(service)
+
+	fmt.Printf("%-8s   %-8s   %-19s   %-24s   %s\n", "REVISION", "STATUS", "REGISTERED", "RUNNING ON", "DOCKER IMAGE")
+	for _, arn := range arns {
+		taskDefinition := taskDefinitions[arn].TaskDefinition
+
+		status := aws.StringValue(taskDefinition.Status)
+		if isPinned(taskDefinitions[arn].Tags) {
+			status = "PINNED"
+		}
+
+		images := make([]string, 0, len(taskDefinition.ContainerDefinitions))
+		for _, containerDefinition := range taskDefinition.ContainerDefinitions {
+			image := aws.StringValue(containerDefinition.Image)
+			if len(taskDefinition.ContainerDefinitions) > 1 {
+				image = *containerDefinition.Name + ": " + image
+			}
+
+			images = append(images, image)
+		}
+
+		runningOn := "-"
+		if services, ok := running[arn]; ok {
+			runningOn = strings.Join(services, ", ")
+			delete(running, arn)
+		}
+
+		fmt.Printf("%-8s   %-8s   %-19s   %-24s   %s\n",
+			getRevisionFromTaskDefinition(arn),
+			status,
+			formatRegisteredAt(taskDefinition),
+			runningOn,
+			strings.Join(images, "\n"+strings.Repeat(" ", 71)),
+		)
+	}
+
+	if len(running) == 0 {
+		return
+	}
+
+	// Services of other families (e.g. <service>-worker) or running a revision
+	// older than the ones listed
+	others := make([]string, 0, len(running))
+	for arn, services := range running {
+		for _, name := range services {
+			others = append(others, fmt.Sprintf("%s: %s revision[%s]", name, getFamilyFromTaskDefinition(arn), getRevisionFromTaskDefinition(arn)))
+		}
+	}
+	sort.Strings(others)
+
+	fmt.Println("\nServices running revisions not listed above:\n  -", strings.Join(others, "\n  - "))
+}
+
+// PruneRevisions deregisters active revisions of service older than the last
+// keep ones, revisions running on any cluster of the region, pinned or labeled
+// are kept.
+func (sess *AWSSession) PruneRevisions(service string, keep int, dryRun bool) {
+	arns := ListTaskDefinitions(sess.Client, service, 0)
+	if len(arns) <= keep {
+		fmt.Printf("Nothing to prune, '%s' has %d active revisions\n", service, len(arns))
+		return
+	}
+
+	candidates := arns[keep:]
+
+	taskDefinitions, err := DescribeTaskDefinitions(sess.Client, candidates)
+	checkErr("DescribeTaskDefinition", err)
+
+	running := sess.getRunningRevisionsInRegion(service)
+
+	labels := make(map[int64][]string)
+	for _, label := range sess.ListLabels(service) {
+		labels[label.Revision] = append(labels[label.Revision], label.Name)
+	}
+
+	toRemove := make([]string, 0, len(candidates))
+	for _, arn := range candidates {
+		revision := getRevisionFromTaskDefinition(arn)
+
+		if services, ok := running[arn]; ok {
+			fmt.Printf("Keeping revision[%s], running on: %s\n", revision, strings.Join(services, ", "))
+			continue
+		}
+		if isPinned(taskDefinitions[arn].Tags) {
+			fmt.Printf("Keeping revision[%s], it's pinned\n", revision)
+			continue
+		}
+		if names, ok := labels[getRevisionNumber(arn)]; ok {
+			fmt.Printf("Keeping revision[%s], labeled as: %s\n", revision, strings.Join(names, ", "))
+			continue
+		}
+
+		toRemove = append(toRemove, arn)
+	}
+
+	if len(toRemove) == 0 {
+		fmt.Printf("Nothing to prune, old revisions of '%s' are running, pinned or labeled\n", service)
+		return
+	}
+
+	fmt.Printf("Following %d revisions of '%s' will be deregistered:\n", len(toRemove), service)
+	for _, arn := range toRemove {
+		fmt.Printf("  - revision[%s] registered at %s\n", getRevisionFromTaskDefinition(arn), formatRegisteredAt(taskDefinitions[arn].TaskDefinition))
+	}
+
+	if dryRun {
+		return
+	}
+
+	fmt.Println("Type CTRL+C to abort")
+	time.Sleep(5 * time.Second)
+
+	for _, arn := range toRemove {
+		DeregisterTaskDefinition(sess.Client, arn)
+		fmt.Printf("Revision[%s] was deregistered\n", getRevisionFromTaskDefinition(arn))
+	}
+}
+
+// RestoreRevision registers a deregistered revision again. ECS doesn't allow
+// to reactivate a revision, so a new one is created with the same content.
+func (sess *AWSSession) RestoreRevision(service string, revision int64) string {
+	taskDefinition := DescribeTaskDefinition(sess.Client, service, revision)
+
+	if aws.StringValue(taskDefinition.Status) == ecs.TaskDefinitionStatusActive {
+		fmt.Printf("Revision[%d] of '%s' is active, nothing to restore\n", revision, service)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Restoring revision[%d] of '%s' as a new revision...\n", revision, service)
+
+	taskDefinition = RegisterTaskDefinition(sess.Client, taskDefinition)
+	return *taskDefinition.TaskDefinitionArn
+}
+
+// PinRevision protects (or not) a revision of being deregistered by prune.
+func (sess *AWSSession) PinRevision(service string, revision int64, pin bool) {
+	arn := sess.GetTaskDefinitionArn(service, revision)
+
+	svc := ecs.New(sess.Client)
+
+	if pin {
+		_, err := svc.TagResource(&ecs.TagResourceInput{
+			ResourceArn: aws.String(arn),
+			Tags: []*ecs.Tag{{
+				Key:   aws.String(pinnedTag),
+				Value: aws.String("true"),
+			}},
+		})
+		checkErr("TagResource", err)
+
+		fmt.Printf("Revision[%s] of '%s' was pinned\n", getRevisionFromTaskDefinition(arn), service)
+		return
+	}
+
+	_, err := svc.UntagResource(&ecs.UntagResourceInput{
+		ResourceArn: aws.String(arn),
+		TagKeys:     aws.StringSlice([]string{pinnedTag}),
+	})
+	checkErr("UntagResource", err)
+
+	fmt.Printf("Revision[%s] of '%s' was unpinned\n", getRevisionFromTaskDefinition(arn), service)
+}
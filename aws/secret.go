@@ -0,0 +1,200 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+type (
+	// SSMMove is a plain variable to be written to a SSM parameter and set as
+	// a secret.
+	SSMMove struct {
+		Key       string
+		Parameter string
+		Value     string
+	}
+)
+
+const (
+	SecretSourceSSM            = "ssm"
+	SecretSourceSecretsManager = "secretsmanager"
+)
+
+// GetSecretSource returns valueFrom prefixed by where the secret is stored,
+// e.g. ssm:/my-service/DATABASE_PASSWORD.
+func GetSecretSource(valueFrom string) string {
+	parts := strings.Split(valueFrom, ":")
+	if len(parts) > 2 && parts[0] == "arn" && parts[2] == SecretSourceSecretsManager {
+		return SecretSourceSecretsManager + ":" + valueFrom
+	}
+
+	return SecretSourceSSM + ":" + valueFrom
+}
+
+// ParseSecretReference converts ssm:<name|arn> or secretsmanager:<name|arn>
+// to the valueFrom of a container secret. Secrets Manager needs the ARN, so
+// names are resolved.
+func (sess *AWSSession) ParseSecretReference(ref string) (string, error) {
+	parts := strings.SplitN(ref, ":", 2)
+	if len(parts) < 2 || strings.EqualFold("", parts[1]) {
+		return "", fmt.Errorf("Invalid secret '%s', use ssm:<name|arn> or secretsmanager:<name|arn>", ref)
+	}
+
+	switch parts[0] {
+	case SecretSourceSSM:
+		return parts[1], nil
+	case SecretSourceSecretsManager:
+		if strings.HasPrefix(parts[1], "arn:") {
+			return parts[1], nil
+		}
+
+		svc := secretsmanager.New(sess.Client)
+
+		resp, err := svc.DescribeSecret(&secretsmanager.DescribeSecretInput{
+			SecretId: aws.String(parts[1]),
+		})
+		if err != nil {
+			return "", fmt.Errorf("Cannot find secret '%s': %s", parts[1], err)
+		}
+
+		return *resp.ARN, nil
+	}
+
+	return "", fmt.Errorf("Invalid secret '%s', use ssm:<name|arn> or secretsmanager:<name|arn>", ref)
+}
+
+// RevealSecret reads the value of a container secret.
+func (sess *AWSSession) RevealSecret(valueFrom string) (string, error) {
+	if !strings.HasPrefix(GetSecretSource(valueFrom), SecretSourceSecretsManager+":") {
+		svc := ssm.New(sess.Client)
+
+		resp, err := svc.GetParameter(&ssm.GetParameterInput{
+			Name:           aws.String(valueFrom),
+			WithDecryption: aws.Bool(true),
+		})
+		if err != nil {
+			return "", err
+		}
+
+		return *resp.Parameter.Value, nil
+	}
+
+	// arn:aws:secretsmanager:region:account:secret:name[:json-key[:version-stage[:version-id]]]
+	parts := strings.Split(valueFrom, ":")
+	if len(parts) < 7 {
+		return "", fmt.Errorf("invalid Secrets Manager ARN '%s'", valueFrom)
+	}
+
+	params := &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(strings.Join(parts[:7], ":")),
+	}
+	if len(parts) > 8 && !strings.EqualFold("", parts[8]) {
+		params.VersionStage = aws.String(parts[8])
+	}
+	if len(parts) > 9 && !strings.EqualFold("", parts[9]) {
+		params.VersionId = aws.String(parts[9])
+	}
+
+	svc := secretsmanager.New(sess.Client)
+
+	resp, err := svc.GetSecretValue(params)
+	if err != nil {
+		return "", err
+	}
+
+	value := aws.StringValue(resp.SecretString)
+
+	if len(parts) > 7 && !strings.EqualFold("", parts[7]) {
+		var values map[string]interface{}
+
+		if err := json.Unmarshal([]byte(value), &values); err != nil {
+			return "", fmt.Errorf("secret is not a json, cannot read key '%s'", parts[7])
+		}
+
+		jsonValue, ok := values[parts[7]]
+		if !ok {
+			return "", fmt.Errorf("key '%s' was not found in secret", parts[7])
+		}
+
+		value = fmt.Sprint(jsonValue)
+	}
+
+	return value, nil
+}
+
+// GetSSMParameterName returns the parameter used when a variable is moved to
+// SSM without a name: /<cluster>/<service>/<key>.
+func (sess *AWSSession) GetSSMParameterName(service, key string) string {
+	return fmt.Sprintf("/%s/%s/%s", sess.Environment.ClusterName, service, key)
+}
+
+// PlanSSMMoves returns the parameter of each variable in moves (key to
+// parameter name) and its plain value, nothing is written yet. Use
+// WriteSSMMoves after the new revision is validated.
+func (sess *AWSSession) PlanSSMMoves(service string, revision int64, container string, moves map[string]string) []SSMMove {
+	taskDefinition := DescribeTaskDefinition(sess.Client, service, revision)
+	def := GetContainerDefinition(taskDefinition, container)
+
+	values := make(map[string]string, len(def.Environment))
+	for _, envvar := range def.Environment {
+		values[*envvar.Name] = *envvar.Value
+	}
+
+	planned := make([]SSMMove, 0, len(moves))
+
+	for key, name := range moves {
+		value, ok := values[key]
+		if !ok {
+			fmt.Printf("Env var '%s' was not found in '%s'\n", key, service)
+			os.Exit(1)
+		}
+
+		if strings.EqualFold("", name) {
+			name = sess.GetSSMParameterName(service, key)
+		}
+
+		planned = append(planned, SSMMove{
+			Key:       key,
+			Parameter: name,
+			Value:     value,
+		})
+	}
+
+	return planned
+}
+
+// WriteSSMMoves writes each value planned by PlanSSMMoves as a SecureString
+// parameter.
+func (sess *AWSSession) WriteSSMMoves(moves []SSMMove, overwrite bool) {
+	svc := ssm.New(sess.Client)
+
+	for _, move := range moves {
+		_, err := svc.PutParameter(&ssm.PutParameterInput{
+			Name:      aws.String(move.Parameter),
+			Value:     aws.String(move.Value),
+			Type:      aws.String(ssm.ParameterTypeSecureString),
+			Overwrite: aws.Bool(overwrite),
+		})
+		if err != nil {
+			fmt.Printf("Cannot write '%s' to SSM parameter '%s': %s\n", move.Key, move.Parameter, err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Env var '%s' was written to SSM parameter '%s'\n", move.Key, move.Parameter)
+	}
+}
+
+// checkExecutionRole warns when secrets are used without an execution role,
+// which ECS needs to read them.
+func checkExecutionRole(taskDefinition *ecs.TaskDefinition) {
+	if taskDefinition.ExecutionRoleArn == nil {
+		fmt.Println("# Task definition has no execution role, ECS needs one to read secrets (task-definition --set execution-role-arn=<arn>)")
+	}
+}
@@ -3,7 +3,6 @@ package aws
 import (
 	"fmt"
 	"os"
-	"strconv"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -26,81 +25,68 @@ func (sess *AWSSession) GetTaskDefinitionArn(service string, revision int64) str
 	return *taskDefinition.TaskDefinitionArn
 }
 
-func (sess *AWSSession) UpdateTaskDefinition(service string, revision int64, changes map[string]string) string {
-	taskDefinition := DescribeTaskDefinition(sess.Client, service, revision)
-
-	var hasChanged bool
-
+// GetContainerDefinition returns the definition of container, when container
+// is empty the task definition must have just one container or one named as
+// its family, otherwise the user needs to choose which one will be used.
+func GetContainerDefinition(taskDefinition *ecs.TaskDefinition, container string) *ecs.ContainerDefinition {
+	names := make([]string, len(taskDefinition.ContainerDefinitions))
 	for k, containerDefinition := range taskDefinition.ContainerDefinitions {
-		for name, value := range changes {
-			switch name {
-			case "cmd":
-				fallthrough
-			case "command":
-				if len(containerDefinition.Command) == 1 {
-					if !strings.EqualFold(*containerDefinition.Command[0], value) {
-						taskDefinition.ContainerDefinitions[k].SetCommand([]*string{aws.String(value)})
-						hasChanged = true
-					}
-				}
-			case "entrypoint":
-				if len(containerDefinition.EntryPoint) == 1 {
-					if !strings.EqualFold(*containerDefinition.EntryPoint[0], value) {
-						taskDefinition.ContainerDefinitions[k].SetEntryPoint([]*string{aws.String(value)})
-						hasChanged = true
-					}
-				}
-			case "image":
-				if !strings.EqualFold(*containerDefinition.Image, value) {
-					taskDefinition.ContainerDefinitions[k].SetImage(value)
-					hasChanged = true
-				}
-			case "cpu":
-				cpu, err := strconv.ParseInt(value, 10, 64)
-				if err != nil {
-					fmt.Println("Value to be set on CPU property is not a valid integer:", err)
-					os.Exit(1)
-				}
-
-				if containerDefinition.Cpu == nil || cpu != *containerDefinition.Cpu {
-					taskDefinition.ContainerDefinitions[k].SetCpu(cpu)
-					hasChanged = true
-				}
-			case "memory":
-				memory, err := strconv.ParseInt(value, 10, 64)
-				if err != nil {
-					fmt.Println("Value to be set on Memory property is not a valid integer:", err)
-					os.Exit(1)
-				}
-
-				if containerDefinition.Memory == nil || memory != *containerDefinition.Memory {
-					taskDefinition.ContainerDefinitions[k].SetMemory(memory)
-					hasChanged = true
-				}
-			case "memory-reservation":
-				memoryReservation, err := strconv.ParseInt(value, 10, 64)
-				if err != nil {
-					fmt.Println("Value to be set on MemoryReservation property is not a valid integer:", err)
-					os.Exit(1)
-				}
-
-				if containerDefinition.MemoryReservation == nil || memoryReservation != *containerDefinition.MemoryReservation {
-					taskDefinition.ContainerDefinitions[k].SetMemoryReservation(memoryReservation)
-					hasChanged = true
-				}
-			default:
-				fmt.Printf("Setting attribute[%s] was not implemented or is unknown", name)
-				os.Exit(1)
+		names[k] = *containerDefinition.Name
+	}
+
+	if !strings.EqualFold("", container) {
+		for _, containerDefinition := range taskDefinition.ContainerDefinitions {
+			if strings.EqualFold(container, *containerDefinition.Name) {
+				return containerDefinition
 			}
 		}
+
+		fmt.Printf("Container '%s' was not found in '%s', options: %s\n", container, *taskDefinition.Family, strings.Join(names, ", "))
+		os.Exit(1)
+	}
+
+	if len(taskDefinition.ContainerDefinitions) == 1 {
+		return taskDefinition.ContainerDefinitions[0]
+	}
+
+	for _, containerDefinition := range taskDefinition.ContainerDefinitions {
+		if strings.EqualFold(*taskDefinition.Family, *containerDefinition.Name) {
+			return containerDefinition
+		}
 	}
 
-	if !hasChanged {
+	fmt.Printf("Task definition '%s' has more than one container, use --container or set a default one, options: %s\n", *taskDefinition.Family, strings.Join(names, ", "))
+	os.Exit(1)
+	return nil
+}
+
+func (sess *AWSSession) UpdateTaskDefinition(service string, revision int64, container string, changes map[string]string) string {
+	taskDefinition := DescribeTaskDefinition(sess.Client, service, revision)
+
+	// Container is chosen only when some container property is changed
+	var containerDefinition *ecs.ContainerDefinition
+	for property := range changes {
+		if !IsTaskProperty(property) {
+			containerDefinition = GetContainerDefinition(taskDefinition, container)
+			break
+		}
+	}
+
+	propertyChanges := applyProperties(taskDefinition, containerDefinition, changes)
+
+	if len(propertyChanges) == 0 {
 		fmt.Println("Nothing to update, current task definition:", *taskDefinition.Revision)
 		return *taskDefinition.TaskDefinitionArn
 	}
 
-	taskDefinition = RegisterTaskDefinition(sess.Client, service, taskDefinition.ContainerDefinitions)
+	fmt.Printf("Changing task definition '%s' revision[%d]", service, *taskDefinition.Revision)
+	if containerDefinition != nil {
+		fmt.Printf(" container[%s]", *containerDefinition.Name)
+	}
+	fmt.Println(":")
+	printPropertyChanges(propertyChanges)
+
+	taskDefinition = RegisterTaskDefinition(sess.Client, taskDefinition)
 	return *taskDefinition.TaskDefinitionArn
 }
 
@@ -122,27 +108,3 @@ func (sess *AWSSession) ListTaskDefinitionStartedWith(startedBy string) []string
 
 	return services
 }
-
-func (sess *AWSSession) ListRevisions(service string) {
-	arns := ListTaskDefinitions(sess.Client, service, 10)
-	if len(arns) == 0 {
-		fmt.Println("No revision was found to this service!")
-		return
-	}
-
-	fmt.Println("REVISION   DOCKER IMAGE")
-	for _, arn := range arns {
-		rev := arn[strings.LastIndex(arn, ":")+1:]
-
-		fmt.Printf("%-8s   ", rev)
-
-		svc := ecs.New(sess.Client)
-		params := &ecs.DescribeTaskDefinitionInput{
-			TaskDefinition: aws.String(arn),
-		}
-		resp, err := svc.DescribeTaskDefinition(params)
-		checkErr("DescribeTaskDefinition", err)
-
-		fmt.Println(*resp.TaskDefinition.ContainerDefinitions[0].Image)
-	}
-}
@@ -0,0 +1,316 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const (
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+	DiffChanged = "changed"
+
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+)
+
+type (
+	FieldDiff struct {
+		Field  string `json:"field"`
+		Status string `json:"status"`
+		Before string `json:"before,omitempty"`
+		After  string `json:"after,omitempty"`
+	}
+
+	ContainerDiff struct {
+		Name   string      `json:"name"`
+		Status string      `json:"status"`
+		Fields []FieldDiff `json:"fields,omitempty"`
+	}
+
+	TaskDefinitionDiff struct {
+		Family     string          `json:"family"`
+		From       int64           `json:"from"`
+		To         int64           `json:"to"`
+		Task       []FieldDiff     `json:"task,omitempty"`
+		Containers []ContainerDiff `json:"containers,omitempty"`
+	}
+
+	diffField struct {
+		name  string
+		value func(interface{}) interface{}
+	}
+)
+
+var (
+	taskDiffFields = []diffField{
+		{"cpu", func(v interface{}) interface{} { return v.(*ecs.TaskDefinition).Cpu }},
+		{"memory", func(v interface{}) interface{} { return v.(*ecs.TaskDefinition).Memory }},
+		{"networkMode", func(v interface{}) interface{} { return v.(*ecs.TaskDefinition).NetworkMode }},
+		{"taskRoleArn", func(v interface{}) interface{} { return v.(*ecs.TaskDefinition).TaskRoleArn }},
+		{"executionRoleArn", func(v interface{}) interface{} { return v.(*ecs.TaskDefinition).ExecutionRoleArn }},
+		{"requiresCompatibilities", func(v interface{}) interface{} { return v.(*ecs.TaskDefinition).RequiresCompatibilities }},
+		{"placementConstraints", func(v interface{}) interface{} { return v.(*ecs.TaskDefinition).PlacementConstraints }},
+		{"volumes", func(v interface{}) interface{} { return v.(*ecs.TaskDefinition).Volumes }},
+	}
+
+	containerDiffFields = []diffField{
+		{"image", func(v interface{}) interface{} { return v.(*ecs.ContainerDefinition).Image }},
+		{"command", func(v interface{}) interface{} { return v.(*ecs.ContainerDefinition).Command }},
+		{"entryPoint", func(v interface{}) interface{} { return v.(*ecs.ContainerDefinition).EntryPoint }},
+		{"cpu", func(v interface{}) interface{} { return v.(*ecs.ContainerDefinition).Cpu }},
+		{"memory", func(v interface{}) interface{} { return v.(*ecs.ContainerDefinition).Memory }},
+		{"memoryReservation", func(v interface{}) interface{} { return v.(*ecs.ContainerDefinition).MemoryReservation }},
+		{"portMappings", func(v interface{}) interface{} { return v.(*ecs.ContainerDefinition).PortMappings }},
+		{"logConfiguration", func(v interface{}) interface{} { return v.(*ecs.ContainerDefinition).LogConfiguration }},
+		{"healthCheck", func(v interface{}) interface{} { return v.(*ecs.ContainerDefinition).HealthCheck }},
+		{"essential", func(v interface{}) interface{} { return v.(*ecs.ContainerDefinition).Essential }},
+		{"stopTimeout", func(v interface{}) interface{} { return v.(*ecs.ContainerDefinition).StopTimeout }},
+		{"workingDirectory", func(v interface{}) interface{} { return v.(*ecs.ContainerDefinition).WorkingDirectory }},
+		{"user", func(v interface{}) interface{} { return v.(*ecs.ContainerDefinition).User }},
+		{"links", func(v interface{}) interface{} { return v.(*ecs.ContainerDefinition).Links }},
+		{"dependsOn", func(v interface{}) interface{} { return v.(*ecs.ContainerDefinition).DependsOn }},
+		{"mountPoints", func(v interface{}) interface{} { return v.(*ecs.ContainerDefinition).MountPoints }},
+		{"dockerLabels", func(v interface{}) interface{} { return v.(*ecs.ContainerDefinition).DockerLabels }},
+		{"ulimits", func(v interface{}) interface{} { return v.(*ecs.ContainerDefinition).Ulimits }},
+	}
+)
+
+func newFieldDiff(field string, before, after string) (FieldDiff, bool) {
+	diff := FieldDiff{
+		Field:  field,
+		Before: before,
+		After:  after,
+	}
+
+	switch {
+	case before == after:
+		return diff, false
+	case before == "<none>":
+		diff.Status = DiffAdded
+		diff.Before = ""
+	case after == "<none>":
+		diff.Status = DiffRemoved
+		diff.After = ""
+	default:
+		diff.Status = DiffChanged
+	}
+
+	return diff, true
+}
+
+func compareFields(fields []diffField, before, after interface{}) []FieldDiff {
+	diffs := make([]FieldDiff, 0)
+
+	for _, field := range fields {
+		if diff, ok := newFieldDiff(field.name, formatPropertyValue(field.value(before)), formatPropertyValue(field.value(after))); ok {
+			diffs = append(diffs, diff)
+		}
+	}
+
+	return diffs
+}
+
+func envvarsToMap(envvars []*ecs.KeyValuePair) map[string]string {
+	m := make(map[string]string, len(envvars))
+	for _, envvar := range envvars {
+		m[*envvar.Name] = *envvar.Value
+	}
+
+	return m
+}
+
+func secretsToMap(secrets []*ecs.Secret) map[string]string {
+	m := make(map[string]string, len(secrets))
+	for _, secret := range secrets {
+		m[*secret.Name] = GetSecretSource(*secret.ValueFrom)
+	}
+
+	return m
+}
+
+// compareEnvvars returns one diff per env var (or secret) added, removed or
+// changed, prefix is added to the variable name. Values are shown by masker.
+func compareEnvvars(prefix string, beforeEnvvars, afterEnvvars map[string]string, masker *Masker) []FieldDiff {
+	keys := make([]string, 0, len(beforeEnvvars)+len(afterEnvvars))
+	for key := range beforeEnvvars {
+		keys = append(keys, key)
+	}
+	for key := range afterEnvvars {
+		if _, ok := beforeEnvvars[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	diffs := make([]FieldDiff, 0)
+
+	for _, key := range keys {
+		beforeValue, inBefore := beforeEnvvars[key]
+		afterValue, inAfter := afterEnvvars[key]
+
+		diff := FieldDiff{
+			Field: prefix + key,
+		}
+
+		switch {
+		case !inBefore:
+			diff.Status = DiffAdded
+			diff.After = formatPropertyValue(masker.Mask(key, afterValue))
+		case !inAfter:
+			diff.Status = DiffRemoved
+			diff.Before = formatPropertyValue(masker.Mask(key, beforeValue))
+		case beforeValue != afterValue:
+			diff.Status = DiffChanged
+			diff.Before = formatPropertyValue(masker.Mask(key, beforeValue))
+			diff.After = formatPropertyValue(masker.Mask(key, afterValue))
+		default:
+			continue
+		}
+
+		diffs = append(diffs, diff)
+	}
+
+	return diffs
+}
+
+// CompareTaskDefinitions returns what changed from before to after, field
+// by field of the task and of each container matched by name. Values of env
+// vars are shown by masker.
+func CompareTaskDefinitions(before, after *ecs.TaskDefinition, masker *Masker) TaskDefinitionDiff {
+	diff := TaskDefinitionDiff{
+		Family: *after.Family,
+		From:   *before.Revision,
+		To:     *after.Revision,
+		Task:   compareFields(taskDiffFields, before, after),
+	}
+
+	beforeContainers := make(map[string]*ecs.ContainerDefinition, len(before.ContainerDefinitions))
+	for _, containerDefinition := range before.ContainerDefinitions {
+		beforeContainers[*containerDefinition.Name] = containerDefinition
+	}
+
+	afterContainers := make(map[string]*ecs.ContainerDefinition, len(after.ContainerDefinitions))
+	for _, containerDefinition := range after.ContainerDefinitions {
+		afterContainers[*containerDefinition.Name] = containerDefinition
+	}
+
+	// Keep the order of containers in the newer revision, removed ones at end
+	for _, containerDefinition := range after.ContainerDefinitions {
+		name := *containerDefinition.Name
+
+		beforeContainer, ok := beforeContainers[name]
+		if !ok {
+			diff.Containers = append(diff.Containers, ContainerDiff{
+				Name:   name,
+				Status: DiffAdded,
+			})
+			continue
+		}
+
+		fields := compareFields(containerDiffFields, beforeContainer, containerDefinition)
+		fields = append(fields, compareEnvvars("env.", envvarsToMap(beforeContainer.Environment), envvarsToMap(containerDefinition.Environment), masker)...)
+		fields = append(fields, compareEnvvars("secret.", secretsToMap(beforeContainer.Secrets), secretsToMap(containerDefinition.Secrets), nil)...)
+
+		if len(fields) > 0 {
+			diff.Containers = append(diff.Containers, ContainerDiff{
+				Name:   name,
+				Status: DiffChanged,
+				Fields: fields,
+			})
+		}
+	}
+
+	for _, containerDefinition := range before.ContainerDefinitions {
+		if _, ok := afterContainers[*containerDefinition.Name]; !ok {
+			diff.Containers = append(diff.Containers, ContainerDiff{
+				Name:   *containerDefinition.Name,
+				Status: DiffRemoved,
+			})
+		}
+	}
+
+	return diff
+}
+
+func (diff TaskDefinitionDiff) HasChanges() bool {
+	return len(diff.Task) > 0 || len(diff.Containers) > 0
+}
+
+func colorize(color, text string, useColor bool) string {
+	if !useColor {
+		return text
+	}
+
+	return color + text + colorReset
+}
+
+func printFieldDiffs(fields []FieldDiff, useColor bool) {
+	for _, field := range fields {
+		switch field.Status {
+		case DiffAdded:
+			fmt.Println(colorize(colorGreen, fmt.Sprintf("  + %s: %s", field.Field, field.After), useColor))
+		case DiffRemoved:
+			fmt.Println(colorize(colorRed, fmt.Sprintf("  - %s: %s", field.Field, field.Before), useColor))
+		default:
+			fmt.Printf("  %s %s: %s -> %s\n",
+				colorize(colorYellow, "~", useColor),
+				field.Field,
+				colorize(colorRed, field.Before, useColor),
+				colorize(colorGreen, field.After, useColor),
+			)
+		}
+	}
+}
+
+// Print writes the diff for humans.
+func (diff TaskDefinitionDiff) Print(useColor bool) {
+	fmt.Printf("# Diff of '%s' revision[%d] -> revision[%d]:\n", diff.Family, diff.From, diff.To)
+
+	if !diff.HasChanges() {
+		fmt.Println("No differences")
+		return
+	}
+
+	if len(diff.Task) > 0 {
+		fmt.Println("task:")
+		printFieldDiffs(diff.Task, useColor)
+	}
+
+	for _, container := range diff.Containers {
+		switch container.Status {
+		case DiffAdded:
+			fmt.Println(colorize(colorGreen, fmt.Sprintf("container '%s' was added", container.Name), useColor))
+		case DiffRemoved:
+			fmt.Println(colorize(colorRed, fmt.Sprintf("container '%s' was removed", container.Name), useColor))
+		default:
+			fmt.Printf("container '%s':\n", container.Name)
+			printFieldDiffs(container.Fields, useColor)
+		}
+	}
+}
+
+// DiffRevisions compares two revisions of service, when to is zero the last
+// revision is used. Colors are used only when stdout is a terminal and
+// sensitive values are masked unless reveal is true.
+func (sess *AWSSession) DiffRevisions(service string, from, to int64, formatJson, noColor, reveal bool) {
+	before := DescribeTaskDefinition(sess.Client, service, from)
+	after := DescribeTaskDefinition(sess.Client, service, to)
+
+	diff := CompareTaskDefinitions(before, after, sess.masker(reveal))
+
+	if formatJson {
+		j, _ := json.MarshalIndent(&diff, "", "   ")
+		fmt.Println(string(j))
+		return
+	}
+
+	diff.Print(!noColor && terminal.IsTerminal(int(os.Stdout.Fd())))
+}
@@ -0,0 +1,372 @@
+package aws
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/private/protocol/json/jsonutil"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	yaml "gopkg.in/yaml.v3"
+)
+
+const (
+	FormatJSON    = "json"
+	FormatYAML    = "yaml"
+	FormatCompose = "compose"
+)
+
+// NewRegisterTaskDefinitionInput returns the input needed to register the
+// same task definition again.
+func NewRegisterTaskDefinitionInput(taskDefinition *ecs.TaskDefinition) *ecs.RegisterTaskDefinitionInput {
+	return &ecs.RegisterTaskDefinitionInput{
+		ContainerDefinitions:    taskDefinition.ContainerDefinitions,
+		Cpu:                     taskDefinition.Cpu,
+		EphemeralStorage:        taskDefinition.EphemeralStorage,
+		ExecutionRoleArn:        taskDefinition.ExecutionRoleArn,
+		Family:                  taskDefinition.Family,
+		InferenceAccelerators:   taskDefinition.InferenceAccelerators,
+		IpcMode:                 taskDefinition.IpcMode,
+		Memory:                  taskDefinition.Memory,
+		NetworkMode:             taskDefinition.NetworkMode,
+		PidMode:                 taskDefinition.PidMode,
+		PlacementConstraints:    taskDefinition.PlacementConstraints,
+		ProxyConfiguration:      taskDefinition.ProxyConfiguration,
+		RequiresCompatibilities: taskDefinition.RequiresCompatibilities,
+		RuntimePlatform:         taskDefinition.RuntimePlatform,
+		TaskRoleArn:             taskDefinition.TaskRoleArn,
+		Volumes:                 taskDefinition.Volumes,
+	}
+}
+
+// GetFileFormat returns format when informed, otherwise it's taken from the
+// extension of filename (ignoring template extensions), JSON is the default.
+// docker-compose.yml and compose.yaml are compose files.
+func GetFileFormat(filename, format string) string {
+	if !strings.EqualFold("", format) {
+		return strings.ToLower(format)
+	}
+
+	if IsComposeFile(filename) {
+		return FormatCompose
+	}
+
+	switch strings.ToLower(filepath.Ext(trimTemplateExtension(filename))) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	}
+
+	return FormatJSON
+}
+
+// removeEmpty removes attributes without value and empty lists or objects,
+// ECS fills some of them when the task definition is registered.
+func removeEmpty(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, attr := range v {
+			attr = removeEmpty(attr)
+			if isEmpty(attr) {
+				delete(v, key)
+				continue
+			}
+
+			v[key] = attr
+		}
+	case []interface{}:
+		for k, item := range v {
+			v[k] = removeEmpty(item)
+		}
+	}
+
+	return value
+}
+
+func isEmpty(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	}
+
+	return false
+}
+
+// taskDefinitionToGeneric returns input as a generic JSON value with the same
+// attribute names of ECS API, without empty attributes.
+func taskDefinitionToGeneric(input *ecs.RegisterTaskDefinitionInput) interface{} {
+	content, err := jsonutil.BuildJSON(input)
+	if err != nil {
+		fmt.Println("Cannot encode task definition:", err)
+		os.Exit(1)
+	}
+
+	var generic interface{}
+
+	err = json.Unmarshal(content, &generic)
+	if err != nil {
+		fmt.Println("Cannot encode task definition:", err)
+		os.Exit(1)
+	}
+
+	return removeEmpty(generic)
+}
+
+// encodeTaskDefinitionJSON returns input as an indented JSON with the same
+// attribute names of ECS API.
+func encodeTaskDefinitionJSON(input *ecs.RegisterTaskDefinitionInput) []byte {
+	content, _ := json.MarshalIndent(taskDefinitionToGeneric(input), "", "  ")
+	return append(content, '\n')
+}
+
+// removeDefaults removes values ECS fills when a task definition is
+// registered, e.g. cpu 0 and essential true, they're the same as not having
+// them.
+func removeDefaults(value interface{}) interface{} {
+	task, ok := value.(map[string]interface{})
+	if !ok {
+		return value
+	}
+
+	// awsvpc and host modes don't map ports, the host port is the container one
+	samePorts := task["networkMode"] == ecs.NetworkModeAwsvpc || task["networkMode"] == ecs.NetworkModeHost
+
+	if task["networkMode"] == ecs.NetworkModeBridge {
+		delete(task, "networkMode")
+	}
+
+	containers, _ := task["containerDefinitions"].([]interface{})
+	for _, container := range containers {
+		def, ok := container.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if def["cpu"] == float64(0) {
+			delete(def, "cpu")
+		}
+		if def["essential"] == true {
+			delete(def, "essential")
+		}
+
+		portMappings, _ := def["portMappings"].([]interface{})
+		for _, portMapping := range portMappings {
+			port, ok := portMapping.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			if port["protocol"] == ecs.TransportProtocolTcp {
+				delete(port, "protocol")
+			}
+			// 0 is a dynamic port, in bridge mode 80:80 is a static one
+			if port["hostPort"] == float64(0) || (samePorts && port["hostPort"] == port["containerPort"]) {
+				delete(port, "hostPort")
+			}
+		}
+	}
+
+	return removeEmpty(task)
+}
+
+// DiffTaskDefinitionInputs compares two task definitions as JSON lines,
+// ignoring defaults filled by ECS.
+func DiffTaskDefinitionInputs(before, after *ecs.RegisterTaskDefinitionInput) LineDiff {
+	lines := func(input *ecs.RegisterTaskDefinitionInput) []string {
+		content, _ := json.MarshalIndent(removeDefaults(taskDefinitionToGeneric(input)), "", "  ")
+		return strings.Split(string(content), "\n")
+	}
+
+	return DiffLines(lines(before), lines(after))
+}
+
+// blockStyle makes yaml use block style instead of JSON style.
+func blockStyle(node *yaml.Node) {
+	switch node.Kind {
+	case yaml.MappingNode, yaml.SequenceNode:
+		node.Style = 0
+	case yaml.ScalarNode:
+		if node.Style == yaml.DoubleQuotedStyle && !strings.Contains(node.Value, "\n") {
+			node.Style = 0
+		}
+	}
+
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+func EncodeTaskDefinition(input *ecs.RegisterTaskDefinitionInput, format string) []byte {
+	content := encodeTaskDefinitionJSON(input)
+
+	switch format {
+	case FormatJSON:
+		return content
+	case FormatYAML:
+		// JSON is a valid YAML, parsing as node keeps the order of attributes
+		var node yaml.Node
+
+		err := yaml.Unmarshal(content, &node)
+		if err != nil {
+			fmt.Println("Cannot encode task definition as yaml:", err)
+			os.Exit(1)
+		}
+
+		blockStyle(&node)
+
+		var buf bytes.Buffer
+
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		encoder.Encode(&node)
+		encoder.Close()
+
+		return buf.Bytes()
+	}
+
+	fmt.Printf("Format '%s' is not supported, use: %s, %s or %s\n", format, FormatJSON, FormatYAML, FormatCompose)
+	os.Exit(1)
+	return nil
+}
+
+func DecodeTaskDefinition(content []byte, format string) *ecs.RegisterTaskDefinitionInput {
+	switch format {
+	case FormatJSON:
+	case FormatYAML:
+		var generic interface{}
+
+		err := yaml.Unmarshal(content, &generic)
+		if err != nil {
+			fmt.Println("Cannot parse task definition as yaml:", err)
+			os.Exit(1)
+		}
+
+		content, err = json.Marshal(generic)
+		if err != nil {
+			fmt.Println("Cannot parse task definition as yaml:", err)
+			os.Exit(1)
+		}
+	default:
+		fmt.Printf("Format '%s' is not supported, use: %s or %s\n", format, FormatJSON, FormatYAML)
+		os.Exit(1)
+	}
+
+	var input ecs.RegisterTaskDefinitionInput
+
+	err := jsonutil.UnmarshalJSONCaseInsensitive(&input, bytes.NewReader(content))
+	if err != nil {
+		fmt.Println("Cannot parse task definition:", err)
+		os.Exit(1)
+	}
+
+	return &input
+}
+
+// ReadTaskDefinitionFile reads a task definition written by
+// ExportTaskDefinition, "-" reads from stdin.
+func ReadTaskDefinitionFile(filename, format string) *ecs.RegisterTaskDefinitionInput {
+	var (
+		content []byte
+		err     error
+	)
+
+	if filename == "-" {
+		content, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		content, err = ioutil.ReadFile(filename)
+	}
+	if err != nil {
+		fmt.Printf("Cannot read '%s': %s\n", filename, err)
+		os.Exit(1)
+	}
+
+	return DecodeTaskDefinition(content, GetFileFormat(filename, format))
+}
+
+// ExportTaskDefinition writes a revision of service to filename (stdout when
+// it's empty or "-"), as a compose file what is not supported is listed.
+func (sess *AWSSession) ExportTaskDefinition(service string, revision int64, filename, format string) {
+	taskDefinition := DescribeTaskDefinition(sess.Client, service, revision)
+
+	var (
+		content  []byte
+		warnings []string
+	)
+
+	format = GetFileFormat(filename, format)
+	if format == FormatCompose {
+		content, warnings = EncodeCompose(taskDefinition)
+	} else {
+		content = EncodeTaskDefinition(NewRegisterTaskDefinitionInput(taskDefinition), format)
+	}
+
+	if strings.EqualFold("", filename) || filename == "-" {
+		// Warnings are already comments in the compose file
+		os.Stdout.Write(content)
+		return
+	}
+
+	err := ioutil.WriteFile(filename, content, 0644)
+	if err != nil {
+		fmt.Printf("Cannot write '%s': %s\n", filename, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Task definition '%s' revision[%d] was exported to '%s'\n", service, *taskDefinition.Revision, filename)
+	if len(warnings) > 0 {
+		fmt.Printf("Warning, not supported by compose:\n  - %s\n", strings.Join(warnings, "\n  - "))
+	}
+}
+
+// PreviewTaskDefinition shows what changes when input is registered to
+// service, the whole task definition when its family doesn't exist yet.
+// Sensitive values are masked unless reveal is true. It returns false when
+// there is nothing to register.
+func (sess *AWSSession) PreviewTaskDefinition(service string, input *ecs.RegisterTaskDefinitionInput, reveal bool) bool {
+	if input.Family == nil || strings.EqualFold("", *input.Family) {
+		input.Family = &service
+	}
+
+	if len(input.ContainerDefinitions) == 0 {
+		fmt.Println("Task definition needs at least one container definition")
+		os.Exit(1)
+	}
+
+	if *input.Family != service {
+		fmt.Printf("Warning: family of the file is '%s', not '%s'\n", *input.Family, service)
+	}
+
+	masker := sess.masker(reveal)
+
+	current := describeTaskDefinitionIfExists(sess.Client, *input.Family)
+	if current == nil {
+		fmt.Printf("New task definition '%s':\n", *input.Family)
+		os.Stdout.Write(EncodeTaskDefinition(maskTaskDefinitionInput(input, masker), FormatYAML))
+		return true
+	}
+
+	// Changes are found on real values, only what is printed is masked
+	currentInput := NewRegisterTaskDefinitionInput(current)
+	if !DiffTaskDefinitionInputs(currentInput, input).HasChanges() {
+		fmt.Printf("Nothing to update, task definition '%s' is equal to revision[%d]\n", *input.Family, *current.Revision)
+		return false
+	}
+
+	maskedDiff := DiffTaskDefinitionInputs(maskTaskDefinitionInput(currentInput, masker), maskTaskDefinitionInput(input, masker))
+
+	fmt.Printf("Changes to task definition '%s' revision[%d]:\n", *input.Family, *current.Revision)
+	if maskedDiff.HasChanges() {
+		maskedDiff.Print(os.Stdout)
+	} else {
+		fmt.Println("  only sensitive values changed, use --reveal to see them")
+	}
+
+	return true
+}
@@ -0,0 +1,84 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+func TestDiffTaskDefinitionInputs(t *testing.T) {
+	input := func(networkMode string, def *ecs.ContainerDefinition) *ecs.RegisterTaskDefinitionInput {
+		def.Name = aws.String("app")
+		def.Image = aws.String("nginx")
+
+		return &ecs.RegisterTaskDefinitionInput{
+			Family:               aws.String("app"),
+			NetworkMode:          stringOrNil(networkMode),
+			ContainerDefinitions: []*ecs.ContainerDefinition{def},
+		}
+	}
+	port := func(containerPort int64, hostPort *int64, protocol *string) []*ecs.PortMapping {
+		return []*ecs.PortMapping{{ContainerPort: aws.Int64(containerPort), HostPort: hostPort, Protocol: protocol}}
+	}
+
+	tests := []struct {
+		name    string
+		before  *ecs.RegisterTaskDefinitionInput
+		after   *ecs.RegisterTaskDefinitionInput
+		changes bool
+	}{
+		{
+			name:   "bridge is the default network mode",
+			before: input("", &ecs.ContainerDefinition{}),
+			after:  input(ecs.NetworkModeBridge, &ecs.ContainerDefinition{}),
+		},
+		{
+			name:   "essential, cpu and protocol filled by ECS",
+			before: input("", &ecs.ContainerDefinition{PortMappings: port(80, nil, nil)}),
+			after:  input("", &ecs.ContainerDefinition{Essential: aws.Bool(true), Cpu: aws.Int64(0), PortMappings: port(80, nil, aws.String("tcp"))}),
+		},
+		{
+			name:   "dynamic host port",
+			before: input(ecs.NetworkModeBridge, &ecs.ContainerDefinition{PortMappings: port(80, nil, nil)}),
+			after:  input(ecs.NetworkModeBridge, &ecs.ContainerDefinition{PortMappings: port(80, aws.Int64(0), nil)}),
+		},
+		{
+			name:    "bridge dynamic to static port",
+			before:  input(ecs.NetworkModeBridge, &ecs.ContainerDefinition{PortMappings: port(80, aws.Int64(0), nil)}),
+			after:   input(ecs.NetworkModeBridge, &ecs.ContainerDefinition{PortMappings: port(80, aws.Int64(80), nil)}),
+			changes: true,
+		},
+		{
+			name:    "bridge static to dynamic port",
+			before:  input("", &ecs.ContainerDefinition{PortMappings: port(80, aws.Int64(80), nil)}),
+			after:   input("", &ecs.ContainerDefinition{PortMappings: port(80, nil, nil)}),
+			changes: true,
+		},
+		{
+			name:   "awsvpc host port is the container port",
+			before: input(ecs.NetworkModeAwsvpc, &ecs.ContainerDefinition{PortMappings: port(80, nil, nil)}),
+			after:  input(ecs.NetworkModeAwsvpc, &ecs.ContainerDefinition{PortMappings: port(80, aws.Int64(80), nil)}),
+		},
+		{
+			name:   "host mode host port is the container port",
+			before: input(ecs.NetworkModeHost, &ecs.ContainerDefinition{PortMappings: port(80, nil, nil)}),
+			after:  input(ecs.NetworkModeHost, &ecs.ContainerDefinition{PortMappings: port(80, aws.Int64(80), nil)}),
+		},
+		{
+			name:    "changed image",
+			before:  input("", &ecs.ContainerDefinition{}),
+			after:   &ecs.RegisterTaskDefinitionInput{Family: aws.String("app"), ContainerDefinitions: []*ecs.ContainerDefinition{{Name: aws.String("app"), Image: aws.String("nginx:2")}}},
+			changes: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diff := DiffTaskDefinitionInputs(test.before, test.after)
+			if diff.HasChanges() != test.changes {
+				t.Errorf("HasChanges is %v, want %v: %v", diff.HasChanges(), test.changes, diff)
+			}
+		})
+	}
+}
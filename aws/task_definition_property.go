@@ -0,0 +1,553 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+type (
+	// PropertyChange is a property changed by UpdateTaskDefinition, values
+	// are formatted to be shown to the user.
+	PropertyChange struct {
+		Property string
+		Before   string
+		After    string
+	}
+)
+
+var (
+	// taskProperties are set on the task definition itself, all others are
+	// set on a container definition.
+	taskProperties = map[string]struct{}{
+		"taskrolearn":      {},
+		"executionrolearn": {},
+		"networkmode":      {},
+		"taskcpu":          {},
+		"taskmemory":       {},
+	}
+
+	networkModes = []string{"bridge", "host", "awsvpc", "none"}
+
+	ulimitNames = []string{
+		"core", "cpu", "data", "fsize", "locks", "memlock", "msgqueue", "nice",
+		"nofile", "nproc", "rss", "rtprio", "rttime", "sigpending", "stack",
+	}
+
+	portMappingRegexp = regexp.MustCompile(`^(?:(\d+):)?(\d+)(?:/(tcp|udp))?$`)
+	ulimitRegexp      = regexp.MustCompile(`^([a-z]+)=(\d+)(?::(\d+))?$`)
+	arnRegexp         = regexp.MustCompile(`^arn:[^:]+:iam::\d{12}:role/.+$`)
+	taskCPURegexp     = regexp.MustCompile(`(?i)^(?:(\d+)|(\d*\.?\d+) ?vcpu)$`)
+	taskMemoryRegexp  = regexp.MustCompile(`(?i)^(?:(\d+)|(\d*\.?\d+) ?gb)$`)
+)
+
+// normalizeProperty allows the same property to be written as "stopTimeout",
+// "stop-timeout" or "stop_timeout", for properties with a key like
+// "log-option.awslogs-group" just the prefix is normalized.
+func normalizeProperty(property string) (string, string) {
+	var key string
+
+	if pos := strings.Index(property, "."); pos > 0 {
+		key = property[pos+1:]
+		property = property[:pos]
+	}
+
+	property = strings.ToLower(property)
+	property = strings.Replace(property, "-", "", -1)
+	property = strings.Replace(property, "_", "", -1)
+
+	return property, key
+}
+
+// IsTaskProperty returns true when property is set on the task definition
+// instead of a container definition.
+func IsTaskProperty(property string) bool {
+	name, _ := normalizeProperty(property)
+	_, ok := taskProperties[name]
+	return ok
+}
+
+// removeNulls removes attributes without value, SDK types have a lot of them.
+func removeNulls(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, attr := range v {
+			if attr == nil {
+				delete(v, key)
+				continue
+			}
+
+			v[key] = removeNulls(attr)
+		}
+	case []interface{}:
+		for k, item := range v {
+			v[k] = removeNulls(item)
+		}
+	}
+
+	return value
+}
+
+func formatPropertyValue(value interface{}) string {
+	var generic interface{}
+
+	j, _ := json.Marshal(value)
+	if err := json.Unmarshal(j, &generic); err == nil {
+		j, _ = json.Marshal(removeNulls(generic))
+	}
+
+	if strings.EqualFold("null", string(j)) || strings.EqualFold("[]", string(j)) || strings.EqualFold("{}", string(j)) {
+		return "<none>"
+	}
+
+	return string(j)
+}
+
+func exitInvalidProperty(property, value string, err interface{}) {
+	fmt.Printf("Value '%s' to be set on property[%s] is not valid: %s\n", value, property, err)
+	os.Exit(1)
+}
+
+func parseInt64Property(property, value string) *int64 {
+	if strings.EqualFold("", value) {
+		return nil
+	}
+
+	i, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		exitInvalidProperty(property, value, "it's not a valid integer")
+	}
+
+	return aws.Int64(i)
+}
+
+func parseStringProperty(value string) *string {
+	if strings.EqualFold("", value) {
+		return nil
+	}
+
+	return aws.String(value)
+}
+
+// parseTaskSizeProperty accepts cpu or memory of the task as a positive
+// integer or with its unit, e.g. "1 vCPU" or "2 GB".
+func parseTaskSizeProperty(property, value string, re *regexp.Regexp, unit string) *string {
+	if strings.EqualFold("", value) {
+		return nil
+	}
+
+	matches := re.FindStringSubmatch(strings.TrimSpace(value))
+	if matches == nil {
+		exitInvalidProperty(property, value, fmt.Sprintf("it must be a positive integer or in %s, e.g. 1 %s", unit, unit))
+	}
+
+	number := matches[1] + matches[2]
+	if size, err := strconv.ParseFloat(number, 64); err != nil || size <= 0 {
+		exitInvalidProperty(property, value, "it must be greater than 0")
+	}
+
+	return aws.String(strings.TrimSpace(value))
+}
+
+// parseStringListProperty accepts a JSON array or a single string, which is
+// used as the only element.
+func parseStringListProperty(property, value string) []*string {
+	if strings.EqualFold("", value) {
+		return nil
+	}
+
+	if !strings.HasPrefix(strings.TrimSpace(value), "[") {
+		return []*string{aws.String(value)}
+	}
+
+	var list []string
+	if err := json.Unmarshal([]byte(value), &list); err != nil {
+		exitInvalidProperty(property, value, err)
+	}
+
+	return aws.StringSlice(list)
+}
+
+func parseJSONProperty(property, value string, v interface{}) {
+	if err := json.Unmarshal([]byte(value), v); err != nil {
+		exitInvalidProperty(property, value, err)
+	}
+}
+
+// parsePortMappingsProperty accepts a JSON array of port mappings or a list
+// as "[hostPort:]containerPort[/protocol]" separated by comma.
+func parsePortMappingsProperty(property, value string) []*ecs.PortMapping {
+	portMappings := make([]*ecs.PortMapping, 0)
+
+	if strings.EqualFold("", value) {
+		return portMappings
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(value), "[") {
+		parseJSONProperty(property, value, &portMappings)
+	} else {
+		for _, mapping := range strings.Split(value, ",") {
+			matches := portMappingRegexp.FindStringSubmatch(strings.TrimSpace(mapping))
+			if matches == nil {
+				exitInvalidProperty(property, value, "use [hostPort:]containerPort[/protocol]")
+			}
+
+			portMapping := &ecs.PortMapping{
+				ContainerPort: parseInt64Property(property, matches[2]),
+				HostPort:      parseInt64Property(property, matches[1]),
+			}
+			if !strings.EqualFold("", matches[3]) {
+				portMapping.Protocol = aws.String(matches[3])
+			}
+
+			portMappings = append(portMappings, portMapping)
+		}
+	}
+
+	for _, portMapping := range portMappings {
+		if portMapping.ContainerPort == nil {
+			exitInvalidProperty(property, value, "containerPort is required")
+		}
+		if portMapping.Protocol != nil && !inArray(*portMapping.Protocol, []string{"tcp", "udp"}) {
+			exitInvalidProperty(property, value, "protocol must be tcp or udp")
+		}
+	}
+
+	return portMappings
+}
+
+func parseHealthCheckProperty(property, value string) *ecs.HealthCheck {
+	if strings.EqualFold("", value) {
+		return nil
+	}
+
+	var healthCheck ecs.HealthCheck
+	parseJSONProperty(property, value, &healthCheck)
+
+	if len(healthCheck.Command) == 0 {
+		exitInvalidProperty(property, value, "command is required")
+	}
+
+	checkRange := func(name string, v *int64, min, max int64) {
+		if v != nil && (*v < min || *v > max) {
+			exitInvalidProperty(property, value, fmt.Sprintf("%s must be between %d and %d", name, min, max))
+		}
+	}
+
+	checkRange("interval", healthCheck.Interval, 5, 300)
+	checkRange("timeout", healthCheck.Timeout, 2, 60)
+	checkRange("retries", healthCheck.Retries, 1, 10)
+	checkRange("startPeriod", healthCheck.StartPeriod, 0, 300)
+
+	return &healthCheck
+}
+
+// parseUlimitsProperty accepts a JSON array of ulimits or a list as
+// "name=soft[:hard]" separated by comma.
+func parseUlimitsProperty(property, value string) []*ecs.Ulimit {
+	ulimits := make([]*ecs.Ulimit, 0)
+
+	if strings.EqualFold("", value) {
+		return ulimits
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(value), "[") {
+		parseJSONProperty(property, value, &ulimits)
+	} else {
+		for _, ulimit := range strings.Split(value, ",") {
+			matches := ulimitRegexp.FindStringSubmatch(strings.TrimSpace(ulimit))
+			if matches == nil {
+				exitInvalidProperty(property, value, "use name=soft[:hard]")
+			}
+
+			hardLimit := matches[3]
+			if strings.EqualFold("", hardLimit) {
+				hardLimit = matches[2]
+			}
+
+			ulimits = append(ulimits, &ecs.Ulimit{
+				Name:      aws.String(matches[1]),
+				SoftLimit: parseInt64Property(property, matches[2]),
+				HardLimit: parseInt64Property(property, hardLimit),
+			})
+		}
+	}
+
+	for _, ulimit := range ulimits {
+		if ulimit.Name == nil || !inArray(*ulimit.Name, ulimitNames) {
+			exitInvalidProperty(property, value, "name must be one of "+strings.Join(ulimitNames, ", "))
+		}
+		if ulimit.SoftLimit == nil || ulimit.HardLimit == nil {
+			exitInvalidProperty(property, value, "softLimit and hardLimit are required")
+		}
+		if *ulimit.SoftLimit > *ulimit.HardLimit {
+			exitInvalidProperty(property, value, "softLimit cannot be greater than hardLimit")
+		}
+	}
+
+	return ulimits
+}
+
+func parseMapProperty(property, value string) map[string]*string {
+	if strings.EqualFold("", value) {
+		return nil
+	}
+
+	var m map[string]string
+	parseJSONProperty(property, value, &m)
+
+	return aws.StringMap(m)
+}
+
+// setMapKey sets key on m, an empty value removes it.
+func setMapKey(m map[string]*string, key, value string) map[string]*string {
+	result := make(map[string]*string, len(m)+1)
+	for k, v := range m {
+		result[k] = v
+	}
+
+	if strings.EqualFold("", value) {
+		delete(result, key)
+	} else {
+		result[key] = aws.String(value)
+	}
+
+	if len(result) == 0 {
+		return nil
+	}
+
+	return result
+}
+
+func parseRoleArnProperty(property, value string) *string {
+	if !strings.EqualFold("", value) && !arnRegexp.MatchString(value) {
+		exitInvalidProperty(property, value, "it's not a valid IAM role ARN")
+	}
+
+	return parseStringProperty(value)
+}
+
+// setContainerProperty sets property on containerDefinition and returns its
+// value before and after the change.
+func setContainerProperty(containerDefinition *ecs.ContainerDefinition, property, value string) (before string, after string) {
+	name, key := normalizeProperty(property)
+
+	if !strings.EqualFold("", key) {
+		switch name {
+		case "logoption":
+			logConfiguration := containerDefinition.LogConfiguration
+			if logConfiguration == nil {
+				exitInvalidProperty(property, value, "container has no log configuration, set log-driver first")
+			}
+
+			before = formatPropertyValue(logConfiguration.Options[key])
+			logConfiguration.Options = setMapKey(logConfiguration.Options, key, value)
+			after = formatPropertyValue(logConfiguration.Options[key])
+		case "dockerlabel":
+			before = formatPropertyValue(containerDefinition.DockerLabels[key])
+			containerDefinition.DockerLabels = setMapKey(containerDefinition.DockerLabels, key, value)
+			after = formatPropertyValue(containerDefinition.DockerLabels[key])
+		default:
+			fmt.Printf("Setting attribute[%s] was not implemented or is unknown\n", property)
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	switch name {
+	case "cmd", "command":
+		before = formatPropertyValue(containerDefinition.Command)
+		containerDefinition.Command = parseStringListProperty(property, value)
+		after = formatPropertyValue(containerDefinition.Command)
+	case "entrypoint":
+		before = formatPropertyValue(containerDefinition.EntryPoint)
+		containerDefinition.EntryPoint = parseStringListProperty(property, value)
+		after = formatPropertyValue(containerDefinition.EntryPoint)
+	case "image":
+		if strings.EqualFold("", value) {
+			exitInvalidProperty(property, value, "image is required")
+		}
+
+		before = formatPropertyValue(containerDefinition.Image)
+		containerDefinition.Image = aws.String(value)
+		after = formatPropertyValue(containerDefinition.Image)
+	case "cpu":
+		before = formatPropertyValue(containerDefinition.Cpu)
+		containerDefinition.Cpu = parseInt64Property(property, value)
+		after = formatPropertyValue(containerDefinition.Cpu)
+	case "memory":
+		before = formatPropertyValue(containerDefinition.Memory)
+		containerDefinition.Memory = parseInt64Property(property, value)
+		after = formatPropertyValue(containerDefinition.Memory)
+	case "memoryreservation":
+		before = formatPropertyValue(containerDefinition.MemoryReservation)
+		containerDefinition.MemoryReservation = parseInt64Property(property, value)
+		after = formatPropertyValue(containerDefinition.MemoryReservation)
+	case "portmappings", "ports":
+		before = formatPropertyValue(containerDefinition.PortMappings)
+		containerDefinition.PortMappings = parsePortMappingsProperty(property, value)
+		after = formatPropertyValue(containerDefinition.PortMappings)
+	case "healthcheck":
+		before = formatPropertyValue(containerDefinition.HealthCheck)
+		containerDefinition.HealthCheck = parseHealthCheckProperty(property, value)
+		after = formatPropertyValue(containerDefinition.HealthCheck)
+	case "logconfiguration":
+		before = formatPropertyValue(containerDefinition.LogConfiguration)
+		if strings.EqualFold("", value) {
+			containerDefinition.LogConfiguration = nil
+		} else {
+			var logConfiguration ecs.LogConfiguration
+			parseJSONProperty(property, value, &logConfiguration)
+			if logConfiguration.LogDriver == nil {
+				exitInvalidProperty(property, value, "logDriver is required")
+			}
+
+			containerDefinition.LogConfiguration = &logConfiguration
+		}
+		after = formatPropertyValue(containerDefinition.LogConfiguration)
+	case "logdriver":
+		before = formatPropertyValue(containerDefinition.LogConfiguration)
+		if strings.EqualFold("", value) {
+			containerDefinition.LogConfiguration = nil
+		} else if containerDefinition.LogConfiguration == nil {
+			containerDefinition.LogConfiguration = &ecs.LogConfiguration{
+				LogDriver: aws.String(value),
+			}
+		} else {
+			containerDefinition.LogConfiguration.LogDriver = aws.String(value)
+		}
+		after = formatPropertyValue(containerDefinition.LogConfiguration)
+	case "logoptions":
+		if containerDefinition.LogConfiguration == nil {
+			exitInvalidProperty(property, value, "container has no log configuration, set log-driver first")
+		}
+
+		before = formatPropertyValue(containerDefinition.LogConfiguration.Options)
+		containerDefinition.LogConfiguration.Options = parseMapProperty(property, value)
+		after = formatPropertyValue(containerDefinition.LogConfiguration.Options)
+	case "dockerlabels":
+		before = formatPropertyValue(containerDefinition.DockerLabels)
+		containerDefinition.DockerLabels = parseMapProperty(property, value)
+		after = formatPropertyValue(containerDefinition.DockerLabels)
+	case "ulimits":
+		before = formatPropertyValue(containerDefinition.Ulimits)
+		containerDefinition.Ulimits = parseUlimitsProperty(property, value)
+		after = formatPropertyValue(containerDefinition.Ulimits)
+	case "essential":
+		essential, err := strconv.ParseBool(value)
+		if err != nil {
+			exitInvalidProperty(property, value, "it's not a valid boolean")
+		}
+
+		before = formatPropertyValue(containerDefinition.Essential)
+		containerDefinition.Essential = aws.Bool(essential)
+		after = formatPropertyValue(containerDefinition.Essential)
+	case "stoptimeout":
+		stopTimeout := parseInt64Property(property, value)
+		if stopTimeout != nil && (*stopTimeout < 2 || *stopTimeout > 120) {
+			exitInvalidProperty(property, value, "it must be between 2 and 120 seconds")
+		}
+
+		before = formatPropertyValue(containerDefinition.StopTimeout)
+		containerDefinition.StopTimeout = stopTimeout
+		after = formatPropertyValue(containerDefinition.StopTimeout)
+	case "workingdirectory", "workdir":
+		before = formatPropertyValue(containerDefinition.WorkingDirectory)
+		containerDefinition.WorkingDirectory = parseStringProperty(value)
+		after = formatPropertyValue(containerDefinition.WorkingDirectory)
+	case "user":
+		before = formatPropertyValue(containerDefinition.User)
+		containerDefinition.User = parseStringProperty(value)
+		after = formatPropertyValue(containerDefinition.User)
+	default:
+		fmt.Printf("Setting attribute[%s] was not implemented or is unknown\n", property)
+		os.Exit(1)
+	}
+
+	return
+}
+
+// setTaskProperty sets property on taskDefinition and returns its value
+// before and after the change.
+func setTaskProperty(taskDefinition *ecs.TaskDefinition, property, value string) (before string, after string) {
+	name, _ := normalizeProperty(property)
+
+	switch name {
+	case "taskrolearn":
+		before = formatPropertyValue(taskDefinition.TaskRoleArn)
+		taskDefinition.TaskRoleArn = parseRoleArnProperty(property, value)
+		after = formatPropertyValue(taskDefinition.TaskRoleArn)
+	case "executionrolearn":
+		before = formatPropertyValue(taskDefinition.ExecutionRoleArn)
+		taskDefinition.ExecutionRoleArn = parseRoleArnProperty(property, value)
+		after = formatPropertyValue(taskDefinition.ExecutionRoleArn)
+	case "networkmode":
+		if !strings.EqualFold("", value) && !inArray(value, networkModes) {
+			exitInvalidProperty(property, value, "it must be one of "+strings.Join(networkModes, ", "))
+		}
+
+		before = formatPropertyValue(taskDefinition.NetworkMode)
+		taskDefinition.NetworkMode = parseStringProperty(strings.ToLower(value))
+		after = formatPropertyValue(taskDefinition.NetworkMode)
+	case "taskcpu":
+		before = formatPropertyValue(taskDefinition.Cpu)
+		taskDefinition.Cpu = parseTaskSizeProperty(property, value, taskCPURegexp, "vCPU")
+		after = formatPropertyValue(taskDefinition.Cpu)
+	case "taskmemory":
+		before = formatPropertyValue(taskDefinition.Memory)
+		taskDefinition.Memory = parseTaskSizeProperty(property, value, taskMemoryRegexp, "GB")
+		after = formatPropertyValue(taskDefinition.Memory)
+	default:
+		fmt.Printf("Setting attribute[%s] was not implemented or is unknown\n", property)
+		os.Exit(1)
+	}
+
+	return
+}
+
+// applyProperties sets every change on taskDefinition, containerDefinition
+// can be nil when just task properties are changed. Properties are applied in
+// alphabetical order, so the output is always the same.
+func applyProperties(taskDefinition *ecs.TaskDefinition, containerDefinition *ecs.ContainerDefinition, changes map[string]string) []PropertyChange {
+	properties := make([]string, 0, len(changes))
+	for property := range changes {
+		properties = append(properties, property)
+	}
+	sort.Strings(properties)
+
+	propertyChanges := make([]PropertyChange, 0, len(changes))
+
+	for _, property := range properties {
+		var before, after string
+
+		if IsTaskProperty(property) {
+			before, after = setTaskProperty(taskDefinition, property, changes[property])
+		} else {
+			before, after = setContainerProperty(containerDefinition, property, changes[property])
+		}
+
+		if before != after {
+			propertyChanges = append(propertyChanges, PropertyChange{
+				Property: property,
+				Before:   before,
+				After:    after,
+			})
+		}
+	}
+
+	return propertyChanges
+}
+
+func printPropertyChanges(propertyChanges []PropertyChange) {
+	for _, change := range propertyChanges {
+		fmt.Printf("  %s:\n    - %s\n    + %s\n", change.Property, change.Before, change.After)
+	}
+}
@@ -0,0 +1,156 @@
+package aws
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/aws/aws-sdk-go/service/ecs"
+	deploy "github.com/guilherme-santos/deploy-ecs"
+	yaml "gopkg.in/yaml.v3"
+)
+
+type (
+	// TemplateData is available inside task definition templates, e.g.
+	// {{ .Environment.ClusterName }} or {{ .Values.image }}.
+	TemplateData struct {
+		Service     string
+		Environment *deploy.Environment
+		Values      map[string]interface{}
+	}
+)
+
+var templateExtensions = []string{".tmpl", ".tpl"}
+
+// IsTemplateFile returns true when filename has a template extension, e.g.
+// task-definition.yaml.tmpl.
+func IsTemplateFile(filename string) bool {
+	return inArray(filepath.Ext(filename), templateExtensions)
+}
+
+// trimTemplateExtension returns the name of the file after rendered.
+func trimTemplateExtension(filename string) string {
+	if IsTemplateFile(filename) {
+		return strings.TrimSuffix(filename, filepath.Ext(filename))
+	}
+
+	return filename
+}
+
+// GetValuesFilename returns the values file used when none is informed, it's
+// values/<cluster-name>.yaml next to the template.
+func GetValuesFilename(templateFilename string, env *deploy.Environment) string {
+	dir := filepath.Join(filepath.Dir(templateFilename), "values")
+
+	for _, ext := range []string{".yaml", ".yml", ".json"} {
+		filename := filepath.Join(dir, env.ClusterName+ext)
+		if _, err := os.Stat(filename); err == nil {
+			return filename
+		}
+	}
+
+	return ""
+}
+
+func readValuesFile(filename string) map[string]interface{} {
+	values := make(map[string]interface{})
+
+	if strings.EqualFold("", filename) {
+		return values
+	}
+
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		fmt.Printf("Cannot read '%s': %s\n", filename, err)
+		os.Exit(1)
+	}
+
+	err = yaml.Unmarshal(content, &values)
+	if err != nil {
+		fmt.Printf("Cannot parse '%s': %s\n", filename, err)
+		os.Exit(1)
+	}
+
+	return values
+}
+
+var templateFuncs = template.FuncMap{
+	// default returns value, or def when value is empty
+	"default": func(def, value interface{}) interface{} {
+		if value == nil || fmt.Sprint(value) == "" {
+			return def
+		}
+
+		return value
+	},
+	// required fails the rendering when value is empty
+	"required": func(msg string, value interface{}) (interface{}, error) {
+		if value == nil || fmt.Sprint(value) == "" {
+			return nil, errors.New(msg)
+		}
+
+		return value, nil
+	},
+	// env returns a variable from local environment
+	"env": os.Getenv,
+}
+
+// RenderTaskDefinitionTemplate executes the template in filename ("-" reads
+// from stdin) using env and the values file, missing keys are errors.
+func RenderTaskDefinitionTemplate(filename, valuesFilename, service string, env *deploy.Environment) []byte {
+	var (
+		content []byte
+		err     error
+	)
+
+	if filename == "-" {
+		content, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		content, err = ioutil.ReadFile(filename)
+	}
+	if err != nil {
+		fmt.Printf("Cannot read '%s': %s\n", filename, err)
+		os.Exit(1)
+	}
+
+	tmpl, err := template.New(filepath.Base(filename)).
+		Option("missingkey=error").
+		Funcs(templateFuncs).
+		Parse(string(content))
+	if err != nil {
+		fmt.Println("Cannot parse template:", err)
+		os.Exit(1)
+	}
+
+	if strings.EqualFold("", valuesFilename) && filename != "-" {
+		valuesFilename = GetValuesFilename(filename, env)
+	}
+
+	data := TemplateData{
+		Service:     service,
+		Environment: env,
+		Values:      readValuesFile(valuesFilename),
+	}
+
+	var buf bytes.Buffer
+
+	err = tmpl.Execute(&buf, data)
+	if err != nil {
+		fmt.Printf("Cannot render template to '%s' environment: %s\n", env.ClusterName, err)
+		os.Exit(1)
+	}
+
+	return buf.Bytes()
+}
+
+// ReadTaskDefinitionTemplate renders the template to the environment of this
+// session and parses the result.
+func (sess *AWSSession) ReadTaskDefinitionTemplate(filename, valuesFilename, format, service string) *ecs.RegisterTaskDefinitionInput {
+	content := RenderTaskDefinitionTemplate(filename, valuesFilename, service, sess.Environment)
+	return DecodeTaskDefinition(content, GetFileFormat(filename, format))
+}
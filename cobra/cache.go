@@ -0,0 +1,50 @@
+package cobra
+
+import (
+	deploy "github.com/guilherme-santos/deploy-ecs"
+	"github.com/guilherme-santos/deploy-ecs/aws"
+	"github.com/spf13/cobra"
+)
+
+func NewCacheCommand(cmd *Command) {
+	cobraCmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage cache of tasks used by ps, logs and exec",
+	}
+
+	var all bool
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List cached tasks of the cluster",
+		Run: func(cobraCmd *cobra.Command, args []string) {
+			aws.PrintTaskCache(getCacheEnvironment(cmd, all))
+		},
+	}
+
+	clearCmd := &cobra.Command{
+		Use:   "clear",
+		Short: "Remove cached tasks of the cluster",
+		Run: func(cobraCmd *cobra.Command, args []string) {
+			aws.ClearTaskCache(getCacheEnvironment(cmd, all))
+		},
+	}
+
+	for _, subCmd := range []*cobra.Command{listCmd, clearCmd} {
+		subCmd.Flags().BoolVar(&all, "all", false, "every cluster instead of the one of --env")
+		cobraCmd.AddCommand(subCmd)
+	}
+
+	cmd.AddCommand(cobraCmd)
+}
+
+// getCacheEnvironment returns the environment of --env, or nil to every
+// cluster.
+func getCacheEnvironment(cmd *Command, all bool) *deploy.Environment {
+	if all {
+		return nil
+	}
+
+	cmd.CheckEnvironment()
+	return cmd.Environment
+}
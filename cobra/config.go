@@ -5,6 +5,7 @@ import (
 	"os"
 	"os/user"
 	"path"
+	"strconv"
 	"strings"
 
 	deploy "github.com/guilherme-santos/deploy-ecs"
@@ -19,7 +20,9 @@ func NewConfigCommand(cmd *Command) {
 	}
 
 	NewConfigEnvironmentsCommand(cmd, cobraCmd)
+	NewConfigServicesCommand(cmd, cobraCmd)
 	NewConfigGitHubCommand(cmd, cobraCmd)
+	NewConfigMaskingCommand(cmd, cobraCmd)
 
 	cmd.AddCommand(cobraCmd)
 }
@@ -42,7 +45,11 @@ func (cmd *Command) LoadConfig() error {
 		config = ini.Empty()
 	}
 
-	cmd.Config = &deploy.Config{}
+	cmd.Config = &deploy.Config{
+		Masking: deploy.MaskingConfig{
+			DetectEntropy: true,
+		},
+	}
 
 	environmentsSec, err := config.GetSection("environment")
 	if err == nil {
@@ -71,6 +78,12 @@ func (cmd *Command) LoadConfig() error {
 					env.Bastion.KeyPair = key.String()
 				}
 			}
+			if key, err := sec.GetKey("proxy_jump"); err == nil {
+				env.JumpHosts = deploy.ParseProxyJump(key.String())
+			}
+			if key, err := sec.GetKey("ecs_port"); err == nil {
+				env.ECSHost.Port = key.String()
+			}
 			if key, err := sec.GetKey("ecs_user"); err == nil {
 				env.ECSHost.User = key.String()
 			}
@@ -82,6 +95,21 @@ func (cmd *Command) LoadConfig() error {
 		}
 	}
 
+	servicesSec, err := config.GetSection("service")
+	if err == nil {
+		for _, sec := range servicesSec.ChildSections() {
+			svc := deploy.ServiceConfig{
+				Name: strings.TrimPrefix(sec.Name(), "service."),
+			}
+
+			if key, err := sec.GetKey("default_container"); err == nil {
+				svc.DefaultContainer = key.String()
+			}
+
+			cmd.Config.Services = append(cmd.Config.Services, svc)
+		}
+	}
+
 	githubSec, err := config.GetSection("github")
 	if err == nil {
 		if key, err := githubSec.GetKey("token"); err == nil {
@@ -92,6 +120,21 @@ func (cmd *Command) LoadConfig() error {
 		}
 	}
 
+	maskingSec, err := config.GetSection("masking")
+	if err == nil {
+		if key, err := maskingSec.GetKey("patterns"); err == nil {
+			cmd.Config.Masking.Patterns = make([]string, 0)
+			for _, pattern := range strings.Split(key.String(), ",") {
+				if pattern = strings.TrimSpace(pattern); !strings.EqualFold("", pattern) {
+					cmd.Config.Masking.Patterns = append(cmd.Config.Masking.Patterns, pattern)
+				}
+			}
+		}
+		if key, err := maskingSec.GetKey("detect_entropy"); err == nil {
+			cmd.Config.Masking.DetectEntropy, _ = key.Bool()
+		}
+	}
+
 	return nil
 }
 
@@ -112,14 +155,37 @@ func (cmd *Command) SaveConfig() error {
 			environmentsSec.NewKey("bastion_user", env.Bastion.User)
 			environmentsSec.NewKey("bastion_key_pair", env.Bastion.KeyPair)
 		}
+		if len(env.JumpHosts) > 0 {
+			environmentsSec.NewKey("proxy_jump", deploy.FormatProxyJump(env.JumpHosts))
+		}
+		if !strings.EqualFold("", env.ECSHost.Port) {
+			environmentsSec.NewKey("ecs_port", env.ECSHost.Port)
+		}
 		environmentsSec.NewKey("ecs_user", env.ECSHost.User)
 		environmentsSec.NewKey("ecs_key_pair", env.ECSHost.KeyPair)
 	}
 
+	if len(cmd.Config.Services) > 0 {
+		iniConfig.NewSection("service")
+	}
+
+	for _, svc := range cmd.Config.Services {
+		servicesSec, _ := iniConfig.NewSection("service." + svc.Name)
+		if !strings.EqualFold("", svc.DefaultContainer) {
+			servicesSec.NewKey("default_container", svc.DefaultContainer)
+		}
+	}
+
 	githubSec, _ := iniConfig.NewSection("github")
 	githubSec.NewKey("token", cmd.Config.GitHub.Token)
 	githubSec.NewKey("repository", cmd.Config.GitHub.DefaultRepository)
 
+	maskingSec, _ := iniConfig.NewSection("masking")
+	if cmd.Config.Masking.Patterns != nil {
+		maskingSec.NewKey("patterns", strings.Join(cmd.Config.Masking.Patterns, ","))
+	}
+	maskingSec.NewKey("detect_entropy", strconv.FormatBool(cmd.Config.Masking.DetectEntropy))
+
 	err := iniConfig.SaveTo(configFile)
 	if err != nil {
 		return fmt.Errorf("cannot save '%s': %s", configFile, err)
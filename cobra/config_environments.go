@@ -30,20 +30,20 @@ func NewConfigEnvironmentsCommand(rootCmd *Command, cmd *cobra.Command) {
 
 			for _, env := range rootCmd.Config.Environments {
 				var (
-					bastion   string
+					jumpHosts string
 					isDefault string
 				)
 
-				if env.HasBastion() {
-					bastion = "yes"
+				if hosts := env.GetJumpHosts(); len(hosts) > 0 {
+					jumpHosts = deploy.FormatProxyJump(hosts)
 				} else {
-					bastion = "no"
+					jumpHosts = "no"
 				}
 				if strings.EqualFold(rootCmd.Config.DefaultEnvironment, env.ClusterName) {
 					isDefault = "(default)"
 				}
 
-				fmt.Printf("  - cluster name: %s, region: %s, bastion: %s %s\n", env.ClusterName, env.Region, bastion, isDefault)
+				fmt.Printf("  - cluster name: %s, region: %s, jump hosts: %s %s\n", env.ClusterName, env.Region, jumpHosts, isDefault)
 			}
 		},
 	})
@@ -99,6 +99,10 @@ func NewConfigEnvironmentsCommand(rootCmd *Command, cmd *cobra.Command) {
 				env.Bastion.KeyPair = askString(scanner, "Bastion KeyPair", "id_rsa")
 			}
 
+			if askBool(scanner, "Do you need other jump hosts to access your ECS machines? (y/n)", nil) {
+				env.JumpHosts = deploy.ParseProxyJump(askString(scanner, "Jump hosts ([user@]host[:port] separated by comma)", ""))
+			}
+
 			fmt.Println("Now, some information to access ec2 machine...")
 
 			env.ECSHost.User = askString(scanner, "ECS User", "ec2-user")
@@ -147,6 +151,13 @@ func NewConfigEnvironmentsCommand(rootCmd *Command, cmd *cobra.Command) {
 				env.Bastion.KeyPair = askString(scanner, "Bastion KeyPair", env.Bastion.KeyPair)
 			}
 
+			hasJumpHosts := len(env.JumpHosts) > 0
+			if askBool(scanner, "Do you need other jump hosts to access your ECS machines? (y/n)", &hasJumpHosts) {
+				env.JumpHosts = deploy.ParseProxyJump(askString(scanner, "Jump hosts ([user@]host[:port] separated by comma)", deploy.FormatProxyJump(env.JumpHosts)))
+			} else {
+				env.JumpHosts = nil
+			}
+
 			fmt.Println("Now, some information to access ec2 machine...")
 
 			env.ECSHost.User = askString(scanner, "ECS User", env.ECSHost.User)
@@ -177,7 +188,7 @@ func NewConfigEnvironmentsCommand(rootCmd *Command, cmd *cobra.Command) {
 
 			environments := make([]deploy.Environment, 0, len(rootCmd.Config.Environments)-1)
 			for _, environment := range rootCmd.Config.Environments {
-				if environment == *env {
+				if strings.EqualFold(environment.ClusterName, env.ClusterName) {
 					continue
 				}
 
@@ -0,0 +1,128 @@
+package cobra
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/guilherme-santos/deploy-ecs/aws"
+	"github.com/spf13/cobra"
+)
+
+func NewConfigMaskingCommand(rootCmd *Command, cmd *cobra.Command) {
+	cobraCmd := &cobra.Command{
+		Use:   "masking",
+		Short: "Manage which env vars are masked in outputs",
+	}
+
+	// getPatterns returns patterns in use, default ones when none was changed
+	getPatterns := func() []string {
+		if rootCmd.Config.Masking.Patterns == nil {
+			return append([]string{}, aws.DefaultMaskPatterns...)
+		}
+
+		return rootCmd.Config.Masking.Patterns
+	}
+
+	cobraCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List key patterns masked and if high-entropy values are detected",
+		Run: func(cobraCmd *cobra.Command, args []string) {
+			fmt.Println("Masked keys:")
+			for _, pattern := range getPatterns() {
+				fmt.Printf("  - %s\n", pattern)
+			}
+
+			fmt.Println("detect high-entropy values:", rootCmd.Config.Masking.DetectEntropy)
+		},
+	})
+
+	cobraCmd.AddCommand(&cobra.Command{
+		Use:   "add <pattern>",
+		Short: "Mask values of keys matching pattern, e.g. *_PASSWORD",
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("command needs an argument: <pattern>")
+			}
+			if _, err := filepath.Match(args[0], ""); err != nil {
+				return fmt.Errorf("Invalid pattern '%s': %s", args[0], err)
+			}
+
+			patterns := getPatterns()
+			for _, pattern := range patterns {
+				if strings.EqualFold(pattern, args[0]) {
+					fmt.Printf("Pattern '%s' is already masked\n", args[0])
+					return nil
+				}
+			}
+
+			rootCmd.Config.Masking.Patterns = append(patterns, args[0])
+			rootCmd.SaveConfig()
+
+			return nil
+		},
+	})
+
+	cobraCmd.AddCommand(&cobra.Command{
+		Use:   "remove <pattern>",
+		Short: "Stop masking values of keys matching pattern",
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("command needs an argument: <pattern>")
+			}
+
+			patterns := make([]string, 0)
+			for _, pattern := range getPatterns() {
+				if !strings.EqualFold(pattern, args[0]) {
+					patterns = append(patterns, pattern)
+				}
+			}
+
+			if len(patterns) == len(getPatterns()) {
+				rootCmd.SilenceUsage = true
+
+				listCmd := cobraCmd.Parent().CommandPath() + " list"
+				return fmt.Errorf("Pattern '%s' was not found, to list type:\n  - %s", args[0], listCmd)
+			}
+
+			rootCmd.Config.Masking.Patterns = patterns
+			rootCmd.SaveConfig()
+
+			return nil
+		},
+	})
+
+	cobraCmd.AddCommand(&cobra.Command{
+		Use:   "reset",
+		Short: "Use default patterns again",
+		Run: func(cobraCmd *cobra.Command, args []string) {
+			rootCmd.Config.Masking.Patterns = nil
+			rootCmd.SaveConfig()
+		},
+	})
+
+	cobraCmd.AddCommand(&cobra.Command{
+		Use:   "detect-entropy <on|off>",
+		Short: "Mask values which look like random tokens, whatever their key is",
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("command needs an argument: <on|off>")
+			}
+
+			switch strings.ToLower(args[0]) {
+			case "on", "true":
+				rootCmd.Config.Masking.DetectEntropy = true
+			case "off", "false":
+				rootCmd.Config.Masking.DetectEntropy = false
+			default:
+				return fmt.Errorf("Invalid value '%s', use: on or off", args[0])
+			}
+
+			rootCmd.SaveConfig()
+			return nil
+		},
+	})
+
+	cmd.AddCommand(cobraCmd)
+}
@@ -0,0 +1,81 @@
+package cobra
+
+import (
+	"errors"
+	"fmt"
+
+	deploy "github.com/guilherme-santos/deploy-ecs"
+	"github.com/spf13/cobra"
+)
+
+func NewConfigServicesCommand(rootCmd *Command, cmd *cobra.Command) {
+	cobraCmd := &cobra.Command{
+		Use:   "services",
+		Short: "Manage preferences of your services",
+	}
+
+	cobraCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List preferences of all services",
+		Run: func(cobraCmd *cobra.Command, args []string) {
+			if len(rootCmd.Config.Services) == 0 {
+				fmt.Println("No services found")
+				return
+			}
+			fmt.Println("List of services:")
+
+			for _, svc := range rootCmd.Config.Services {
+				fmt.Printf("  - service: %s, default container: %s\n", svc.Name, svc.DefaultContainer)
+			}
+		},
+	})
+
+	cobraCmd.AddCommand(&cobra.Command{
+		Use:   "set-default-container <service> <container>",
+		Short: "Set container changed by env and task-definition when --container is not informed",
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			if len(args) < 2 {
+				return errors.New("command needs two arguments: <service> <container>")
+			}
+
+			svc := rootCmd.Config.GetService(args[0])
+			if svc == nil {
+				rootCmd.Config.Services = append(rootCmd.Config.Services, deploy.ServiceConfig{
+					Name: args[0],
+				})
+				svc = &rootCmd.Config.Services[len(rootCmd.Config.Services)-1]
+			}
+
+			svc.DefaultContainer = args[1]
+			rootCmd.SaveConfig()
+
+			fmt.Printf("Set container '%s' as default of '%s'\n", args[1], args[0])
+			return nil
+		},
+	})
+
+	cobraCmd.AddCommand(&cobra.Command{
+		Use:   "unset-default-container <service>",
+		Short: "Remove default container of a service",
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("command needs an argument: <service>")
+			}
+
+			svc := rootCmd.Config.GetService(args[0])
+			if svc == nil {
+				rootCmd.SilenceUsage = true
+
+				listCmd := cobraCmd.Parent().CommandPath() + " list"
+				return fmt.Errorf("Service '%s' was not found, to list type:\n  - %s", args[0], listCmd)
+			}
+
+			svc.DefaultContainer = ""
+			rootCmd.SaveConfig()
+
+			return nil
+		},
+	})
+
+	cmd.AddCommand(cobraCmd)
+}
@@ -4,9 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/guilherme-santos/deploy-ecs/aws"
 	"github.com/guilherme-santos/deploy-ecs/shell"
 	"github.com/spf13/cobra"
 )
@@ -18,16 +20,22 @@ func NewDeployCommand(cmd *Command) {
 	}
 
 	var (
-		revision    int64
+		revision    string
+		label       string
+		container   string
 		tagOrBranch string
 		rebuild     bool
 		wait        bool
+		force       bool
 	)
 
-	cobraCmd.Flags().Int64Var(&revision, "revision", 0, "revision number, if not present will use last one")
+	cobraCmd.Flags().StringVar(&revision, "revision", "", "revision number or label to be deployed")
+	cobraCmd.Flags().StringVar(&label, "label", "", "label of the revision to be deployed")
+	cobraCmd.Flags().StringVar(&container, "container", "", "container which receives the new image, required when task has more than one container and no default one")
 	cobraCmd.Flags().StringVarP(&tagOrBranch, "tag", "t", "", "tag or branch name to build and deploy")
 	cobraCmd.Flags().BoolVar(&rebuild, "rebuild", false, "force rebuild image even it already cached")
 	cobraCmd.Flags().BoolVar(&wait, "wait", false, "wait until services are stable")
+	cobraCmd.Flags().BoolVar(&force, "force", false, "deploy even when env vars don't match the env schema")
 
 	cobraCmd.PreRun = func(cobraCmd *cobra.Command, args []string) {
 		cmd.CheckService()
@@ -40,23 +48,31 @@ func NewDeployCommand(cmd *Command) {
 		if !strings.EqualFold("", tagOrBranch) {
 			deployTag = true
 		}
-		if revision != 0 {
+		if !strings.EqualFold("", revision) && !strings.EqualFold("", label) {
+			return errors.New("command needs just one argument: --revision OR --label")
+		}
+		if !strings.EqualFold("", label) {
+			revision = label
+		}
+		if !strings.EqualFold("", revision) {
 			deployRevision = true
 		}
 
 		if !(deployTag || deployRevision) {
-			return errors.New("command needs an argument: --tag OR --revision")
+			return errors.New("command needs an argument: --tag OR --revision OR --label")
 		}
 
 		if deployTag && deployRevision {
-			return errors.New("command needs just one argument: --tag OR --revision")
+			return errors.New("command needs just one argument: --tag OR --revision OR --label")
 		}
 
 		var taskDefinitions map[string]string
 
 		if deployRevision {
 			taskDefinitions = make(map[string]string)
-			taskDefinitions[cmd.ServiceName] = cmd.AWSSession.GetTaskDefinitionArn(cmd.ServiceName, revision)
+			taskDefinitions[cmd.ServiceName] = cmd.AWSSession.ResolveTaskDefinitionArn(cmd.ServiceName, revision)
+
+			checkEnvSchemas(cmd, taskDefinitions, force)
 		} else {
 			if !shell.IsGitInstalled() {
 				fmt.Println("The program 'git' is currently not installed.")
@@ -67,8 +83,22 @@ func NewDeployCommand(cmd *Command) {
 				os.Exit(1)
 			}
 
+			services := cmd.AWSSession.ListTaskDefinitionStartedWith(cmd.ServiceName)
+			if len(services) == 0 {
+				fmt.Printf("No task-definition started with '%s'.\n", cmd.ServiceName)
+				os.Exit(1)
+			}
+
+			// Only the image changes, so last revisions are validated before
+			// anything is built or registered
+			current := make(map[string]string, len(services))
+			for _, service := range services {
+				current[service] = cmd.AWSSession.GetTaskDefinitionArn(service, 0)
+			}
+			checkEnvSchemas(cmd, current, force)
+
 			image := generateDockerImage(cmd, tagOrBranch, rebuild)
-			taskDefinitions = updateImageOfTaskDefinitions(cmd, image)
+			taskDefinitions = updateImageOfTaskDefinitions(cmd, services, container, image)
 		}
 
 		doDeploy(cmd, taskDefinitions, wait)
@@ -107,17 +137,30 @@ func generateDockerImage(cmd *Command, tagOrBranch string, rebuild bool) string
 	return cmd.AWSSession.PushImageToAws(cmd.Service.Name, tagOrBranch)
 }
 
-func updateImageOfTaskDefinitions(cmd *Command, image string) map[string]string {
-	services := cmd.AWSSession.ListTaskDefinitionStartedWith(cmd.ServiceName)
-	if len(services) == 0 {
-		fmt.Printf("No task-definition started with '%s'.\n", cmd.ServiceName)
-		os.Exit(1)
+// checkEnvSchemas validates the revision (arn) of each service, violations of
+// every service are shown before the command stops.
+func checkEnvSchemas(cmd *Command, taskDefinitions map[string]string, force bool) {
+	services := make([]string, 0, len(taskDefinitions))
+	for service := range taskDefinitions {
+		services = append(services, service)
 	}
+	sort.Strings(services)
+
+	reports := make([]string, 0)
+	for _, service := range services {
+		if report := cmd.AWSSession.RevisionEnvSchemaReport(service, taskDefinitions[service]); !strings.EqualFold("", report) {
+			reports = append(reports, report)
+		}
+	}
+
+	aws.CheckEnvSchemaReports(reports, force)
+}
 
+func updateImageOfTaskDefinitions(cmd *Command, services []string, container, image string) map[string]string {
 	taskDefinitions := make(map[string]string)
 
 	for _, service := range services {
-		taskDefinition := cmd.AWSSession.UpdateTaskDefinition(service, 0, map[string]string{
+		taskDefinition := cmd.AWSSession.UpdateTaskDefinition(service, 0, cmd.getContainerName(service, container), map[string]string{
 			"image": image,
 		})
 
@@ -0,0 +1,61 @@
+package cobra
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+func NewDiffRevisionsCommand(cmd *Command) {
+	cmd.AddCommand(newDiffRevisionsCommand(cmd, "diff-revisions"))
+}
+
+// newDiffRevisionsCommand is used by diff-revisions and task-definition diff.
+func newDiffRevisionsCommand(cmd *Command, use string) *cobra.Command {
+	cobraCmd := &cobra.Command{
+		Use:   use + " <revision> [revision]",
+		Short: "Show what changed between two revisions, if second one is not present will use last one",
+	}
+
+	var (
+		formatJson bool
+		noColor    bool
+		reveal     bool
+	)
+
+	cobraCmd.Flags().BoolVar(&formatJson, "json", false, "print diff as json")
+	cobraCmd.Flags().BoolVar(&noColor, "no-color", false, "disable colored output")
+	cobraCmd.Flags().BoolVar(&reveal, "reveal", false, "show values of sensitive env vars instead of masking them")
+
+	cobraCmd.PreRun = func(cobraCmd *cobra.Command, args []string) {
+		cmd.CheckService()
+		cmd.CheckEnvironment()
+	}
+
+	cobraCmd.RunE = func(cobraCmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return errors.New("command needs at least one argument: <revision>")
+		}
+
+		revisions := make([]int64, 2)
+		for k, arg := range args {
+			if k >= len(revisions) {
+				return errors.New("command accepts at most two revisions")
+			}
+
+			revision, err := strconv.ParseInt(arg, 10, 64)
+			if err != nil || revision <= 0 {
+				return fmt.Errorf("Invalid revision '%s'", arg)
+			}
+
+			revisions[k] = revision
+		}
+
+		cmd.AWSSession.DiffRevisions(cmd.ServiceName, revisions[0], revisions[1], formatJson, noColor, reveal)
+		return nil
+	}
+
+	return cobraCmd
+}
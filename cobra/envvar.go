@@ -4,8 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/guilherme-santos/deploy-ecs/aws"
 	"github.com/spf13/cobra"
 )
 
@@ -18,23 +21,40 @@ func NewEnvvarCommand(cmd *Command) {
 
 	var (
 		revision    int64
+		container   string
 		formatJson  bool
 		gets        []string
 		sets        []string
 		unsets      []string
+		setSecrets  []string
+		moveToSSM   []string
+		overwrite   bool
+		reveal      bool
+		fileOptions aws.EnvFileOptions
 		deploy      bool
 		waitDeploy  bool
 		allServices bool
+		force       bool
 	)
 
 	cobraCmd.Flags().Int64Var(&revision, "revision", 0, "revision number, if not present will use last one")
+	cobraCmd.Flags().StringVar(&container, "container", "", "container name, required when task has more than one container and no default one")
 	cobraCmd.Flags().BoolVar(&formatJson, "json", false, "If envvars should be formated as json")
 	cobraCmd.Flags().StringArrayVar(&gets, "get", nil, "key to be read (can be used multiple times)")
 	cobraCmd.Flags().StringArrayVar(&sets, "set", nil, "key=value to be updated (can be used multiple times)")
 	cobraCmd.Flags().StringArrayVar(&unsets, "unset", nil, "key to be removed (can be used multiple times)")
+	cobraCmd.Flags().StringArrayVar(&setSecrets, "set-secret", nil, "key=ssm:<name|arn> or key=secretsmanager:<name|arn> to be set as secret (can be used multiple times)")
+	cobraCmd.Flags().StringArrayVar(&moveToSSM, "move-to-ssm", nil, "key[=parameter] to be moved to SSM, default parameter is /<env>/<service>/<key> (can be used multiple times)")
+	cobraCmd.Flags().BoolVar(&overwrite, "overwrite-ssm", false, "overwrite SSM parameter when it exists, should be used with --move-to-ssm flag")
+	cobraCmd.Flags().BoolVar(&reveal, "reveal", false, "show values of sensitive env vars and secrets instead of masking them")
+	cobraCmd.Flags().BoolVar(&fileOptions.Interpolate, "interpolate", false, "replace ${VAR} in values read from stdin by earlier keys or local env vars")
+	cobraCmd.Flags().StringVar(&fileOptions.Separator, "json-separator", "_", "separator of keys when nested json objects are flattened")
+	cobraCmd.Flags().StringVar(&fileOptions.Case, "json-case", aws.KeyCaseKeep, "case of keys read from json: keep, upper or lower")
+	cobraCmd.Flags().StringVar(&fileOptions.ArrayJoin, "json-array", "", "how json arrays are converted: comma, space, json or index, if not present arrays are rejected")
 	cobraCmd.Flags().BoolVar(&deploy, "deploy", false, "change env var and redeploy")
 	cobraCmd.Flags().BoolVar(&waitDeploy, "wait", false, "should be used with --deploy flag")
 	cobraCmd.Flags().BoolVar(&allServices, "all", false, "update this current service and all its children <service>-*, should be used just when you're setting envvars")
+	cobraCmd.Flags().BoolVar(&force, "force", false, "register and deploy even when env vars don't match the env schema")
 
 	cobraCmd.PreRun = func(cobraCmd *cobra.Command, args []string) {
 		cmd.CheckService()
@@ -58,24 +78,119 @@ func NewEnvvarCommand(cmd *Command) {
 
 		stdin, _ := os.Stdin.Stat()
 		if (stdin.Mode()&os.ModeCharDevice) != os.ModeCharDevice && stdin.Size() > 0 {
-			if len(sets) > 0 || len(unsets) > 0 {
-				return errors.New("Cannot update envvar: use stdin or --set / --unset not both")
+			if len(sets) > 0 || len(unsets) > 0 || len(setSecrets) > 0 || len(moveToSSM) > 0 {
+				return errors.New("Cannot update envvar: use stdin or --set / --unset / --set-secret / --move-to-ssm not both")
 			}
 
+			// stdin can be read just once, the same document is applied to each family
+			fileOptions.Json = formatJson
+			envvars := aws.ReadEnvvarFile(os.Stdin, fileOptions)
+
+			names := make([]string, 0, len(services))
 			for service := range services {
-				changes, unsets := cmd.AWSSession.DiffEnvvarFromFile(cmd.ServiceName, revision, os.Stdin, formatJson)
-				services[service] = cmd.AWSSession.UpdateEnvvar(cmd.ServiceName, revision, changes, unsets)
+				names = append(names, service)
+			}
+			sort.Strings(names)
+
+			// Every family is validated before any revision is registered
+			updates := make(map[string]*ecs.TaskDefinition, len(names))
+			diffs := make(map[string]aws.EnvvarChanges, len(names))
+			reports := make([]string, 0)
+			for _, service := range names {
+				containerName := cmd.getContainerName(service, container)
+				changes, unsets, summary := cmd.AWSSession.DiffEnvvars(service, revision, containerName, envvars)
+				diffs[service] = summary
+
+				updates[service] = cmd.AWSSession.PrepareEnvvarUpdate(service, revision, containerName, changes, unsets, nil)
+				if updates[service] == nil {
+					continue
+				}
+
+				if report := aws.EnvSchemaReport(service, updates[service]); !strings.EqualFold("", report) {
+					reports = append(reports, report)
+				}
 			}
-		} else if len(sets) == 0 && len(unsets) == 0 {
+
+			aws.CheckEnvSchemaReports(reports, force)
+
+			summaries := make([]string, 0, len(names))
+			for _, service := range names {
+				summary := diffs[service]
+				if updates[service] != nil {
+					services[service] = cmd.AWSSession.RegisterEnvvarUpdate(updates[service])
+				}
+
+				if services[service] > 0 {
+					summaries = append(summaries, fmt.Sprintf("%s: revision[%d], %s", service, services[service], summary))
+				} else {
+					summaries = append(summaries, fmt.Sprintf("%s: %s", service, summary))
+				}
+			}
+
+			fmt.Printf("# Summary:\n  - %s\n", strings.Join(summaries, "\n  - "))
+		} else if len(sets) == 0 && len(unsets) == 0 && len(setSecrets) == 0 && len(moveToSSM) == 0 {
 			if allServices {
 				return errors.New("Cannot use --all to get envvars")
 			}
 
-			cmd.AWSSession.GetEnvvar(cmd.ServiceName, revision, gets, formatJson)
+			cmd.AWSSession.GetEnvvar(cmd.ServiceName, revision, cmd.getContainerName(cmd.ServiceName, container), gets, formatJson, reveal)
 			return nil
 		} else {
+			secrets, err := parseSecrets(cmd, setSecrets)
+			if err != nil {
+				return err
+			}
+
+			moves := make(map[string]string, len(moveToSSM))
+			for _, move := range moveToSSM {
+				parts := strings.SplitN(move, "=", 2)
+
+				var name string
+				if len(parts) > 1 {
+					name = parts[1]
+				}
+
+				moves[parts[0]] = name
+			}
+
+			changes, removes := parseEnvvarChanges(sets, unsets)
+
+			// Every family is validated before any parameter is written or any
+			// revision is registered
+			updates := make(map[string]*ecs.TaskDefinition, len(services))
+			ssmMoves := make(map[string][]aws.SSMMove, len(services))
+			reports := make([]string, 0)
 			for service := range services {
-				services[service] = updateEnvvar(cmd, service, revision, sets, unsets)
+				containerName := cmd.getContainerName(service, container)
+
+				serviceSecrets := make(map[string]string, len(secrets)+len(moves))
+				for key, valueFrom := range secrets {
+					serviceSecrets[key] = valueFrom
+				}
+				if len(moves) > 0 {
+					ssmMoves[service] = cmd.AWSSession.PlanSSMMoves(service, revision, containerName, moves)
+					for _, move := range ssmMoves[service] {
+						serviceSecrets[move.Key] = move.Parameter
+					}
+				}
+
+				updates[service] = cmd.AWSSession.PrepareEnvvarUpdate(service, revision, containerName, changes, removes, serviceSecrets)
+				if updates[service] == nil {
+					continue
+				}
+
+				if report := aws.EnvSchemaReport(service, updates[service]); !strings.EqualFold("", report) {
+					reports = append(reports, report)
+				}
+			}
+
+			aws.CheckEnvSchemaReports(reports, force)
+
+			for service, taskDefinition := range updates {
+				if taskDefinition != nil {
+					cmd.AWSSession.WriteSSMMoves(ssmMoves[service], overwrite)
+					services[service] = cmd.AWSSession.RegisterEnvvarUpdate(taskDefinition)
+				}
 			}
 		}
 
@@ -85,25 +200,47 @@ func NewEnvvarCommand(cmd *Command) {
 
 		for service, revision := range services {
 			if revision > 0 {
-				deployArgs := []string{"deploy", "--service", service, "--revision", fmt.Sprint(revision)}
-				if waitDeploy {
-					deployArgs = append(deployArgs, "--wait")
-				}
-
-				cmd.SetArgs(deployArgs)
-				if err := cmd.Execute(); err != nil {
-					return fmt.Errorf("Cannot deploy new revision to '%s': %s", service, err.Error())
-				}
+				deployRevision(cmd, service, revision, waitDeploy, force)
 			}
 		}
 
 		return nil
 	}
 
+	cobraCmd.AddCommand(newEnvvarEditCommand(cmd))
+	cobraCmd.AddCommand(newEnvvarDiffCommand(cmd))
+	cobraCmd.AddCommand(newEnvvarCopyCommand(cmd))
+	cobraCmd.AddCommand(newEnvvarHistoryCommand(cmd))
+	cobraCmd.AddCommand(newEnvvarExecCommand(cmd))
+
 	cmd.AddCommand(cobraCmd)
 }
 
-func updateEnvvar(cmd *Command, serviceName string, revision int64, sets []string, unsets []string) int64 {
+// parseSecrets converts key=ssm:<name|arn> or key=secretsmanager:<name|arn>
+// to the valueFrom of each key.
+func parseSecrets(cmd *Command, setSecrets []string) (map[string]string, error) {
+	secrets := make(map[string]string, len(setSecrets))
+
+	for _, secret := range setSecrets {
+		parts := strings.SplitN(secret, "=", 2)
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("Invalid secret '%s', use key=ssm:<name|arn> or key=secretsmanager:<name|arn>", secret)
+		}
+
+		valueFrom, err := cmd.AWSSession.ParseSecretReference(parts[1])
+		if err != nil {
+			return nil, err
+		}
+
+		secrets[parts[0]] = valueFrom
+	}
+
+	return secrets, nil
+}
+
+// parseEnvvarChanges converts key=value of sets and keys of unsets to what
+// PrepareEnvvarUpdate expects.
+func parseEnvvarChanges(sets []string, unsets []string) (map[string]string, map[string]struct{}) {
 	changes := make(map[string]string)
 	for _, change := range sets {
 		parts := strings.SplitN(change, "=", 2)
@@ -121,5 +258,5 @@ func updateEnvvar(cmd *Command, serviceName string, revision int64, sets []strin
 		removes[field] = struct{}{}
 	}
 
-	return cmd.AWSSession.UpdateEnvvar(serviceName, revision, changes, removes)
+	return changes, removes
 }
@@ -0,0 +1,122 @@
+package cobra
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/guilherme-santos/deploy-ecs/aws"
+	"github.com/guilherme-santos/deploy-ecs/shell"
+	"github.com/spf13/cobra"
+)
+
+func newEnvvarCopyCommand(cmd *Command) *cobra.Command {
+	cobraCmd := &cobra.Command{
+		Use:   "copy",
+		Short: "Copy env vars from another environment or service",
+	}
+
+	var (
+		fromEnv       string
+		toEnv         string
+		fromService   string
+		fromRevision  int64
+		fromContainer string
+		container     string
+		includes      []string
+		excludes      []string
+		yes           bool
+		deploy        bool
+		waitDeploy    bool
+		reveal        bool
+		force         bool
+	)
+
+	cobraCmd.Flags().StringVar(&fromEnv, "from-env", "", "environment to copy from, if not present will use --env")
+	cobraCmd.Flags().StringVar(&toEnv, "to-env", "", "environment to copy to, if not present will use --env")
+	cobraCmd.Flags().StringVar(&fromService, "from-service", "", "service to copy from, if not present will use --service")
+	cobraCmd.Flags().Int64Var(&fromRevision, "from-revision", 0, "revision to copy from, if not present will use last one")
+	cobraCmd.Flags().StringVar(&fromContainer, "from-container", "", "container to copy from, if not present will use --container")
+	cobraCmd.Flags().StringVar(&container, "container", "", "container name, required when task has more than one container and no default one")
+	cobraCmd.Flags().StringArrayVar(&includes, "include", nil, "copy only keys matching this pattern, e.g. DATABASE_* (can be used multiple times)")
+	cobraCmd.Flags().StringArrayVar(&excludes, "exclude", nil, "don't copy keys matching this pattern (can be used multiple times)")
+	cobraCmd.Flags().BoolVarP(&yes, "yes", "y", false, "don't ask for confirmation")
+	cobraCmd.Flags().BoolVar(&deploy, "deploy", false, "deploy new revision")
+	cobraCmd.Flags().BoolVar(&waitDeploy, "wait", false, "should be used with --deploy flag")
+	cobraCmd.Flags().BoolVar(&reveal, "reveal", false, "show values of sensitive env vars in the preview instead of masking them")
+	cobraCmd.Flags().BoolVar(&force, "force", false, "register and deploy even when env vars don't match the env schema")
+
+	cobraCmd.PreRun = func(cobraCmd *cobra.Command, args []string) {
+		cmd.CheckService()
+		cmd.CheckEnvironment()
+	}
+
+	cobraCmd.RunE = func(cobraCmd *cobra.Command, args []string) error {
+		if strings.EqualFold("", fromEnv) {
+			fromEnv = cmd.env
+		}
+		if strings.EqualFold("", toEnv) {
+			toEnv = cmd.env
+		}
+		if strings.EqualFold("", fromService) {
+			fromService = cmd.ServiceName
+		}
+
+		toContainer := cmd.getContainerName(cmd.ServiceName, container)
+		if strings.EqualFold("", fromContainer) {
+			fromContainer = cmd.getContainerName(fromService, container)
+		}
+
+		if fromEnv == toEnv && fromService == cmd.ServiceName && fromContainer == toContainer {
+			return errors.New("command needs a source different of the target: --from-env, --to-env, --from-service or --from-container")
+		}
+
+		fromSession := cmd.getAWSSession(fromEnv)
+		toSession := cmd.getAWSSession(toEnv)
+
+		source := fromSession.GetEnvvarSet(fromService, fromRevision, fromContainer)
+
+		changes, err := aws.FilterEnvvars(source.Envvars, includes, excludes)
+		if err != nil {
+			return err
+		}
+
+		secrets, _ := aws.FilterEnvvars(source.Secrets, includes, excludes)
+		if len(secrets) > 0 {
+			keys := make([]string, 0, len(secrets))
+			for key := range secrets {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+
+			fmt.Printf("# Secrets are not copied since they usually point to each environment: %s\n", strings.Join(keys, ", "))
+		}
+
+		if len(changes) == 0 {
+			fmt.Printf("No env vars to copy from '%s' %s\n", fromService, source.Label)
+			return nil
+		}
+
+		fmt.Printf("# Copying env vars from '%s' %s\n", fromService, source.Label)
+
+		if !toSession.PreviewEnvvarChanges(cmd.ServiceName, 0, toContainer, changes, nil, reveal) {
+			fmt.Println("Nothing to update in this task definition")
+			return nil
+		}
+
+		if !yes && !shell.Confirm(fmt.Sprintf("Register a new revision of '%s' on '%s' with these changes?", cmd.ServiceName, toEnv)) {
+			fmt.Println("Nothing was changed")
+			return nil
+		}
+
+		revision := toSession.UpdateEnvvar(cmd.ServiceName, 0, toContainer, changes, nil, nil, force)
+		if deploy && revision > 0 {
+			deployRevisionToEnv(cmd, toEnv, cmd.ServiceName, revision, waitDeploy, force)
+		}
+
+		return nil
+	}
+
+	return cobraCmd
+}
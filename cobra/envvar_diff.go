@@ -0,0 +1,65 @@
+package cobra
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func newEnvvarDiffCommand(cmd *Command) *cobra.Command {
+	cobraCmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Compare env vars of two environments or two revisions",
+	}
+
+	var (
+		fromEnv      string
+		toEnv        string
+		fromRevision int64
+		toRevision   int64
+		container    string
+		reveal       bool
+		exitCode     bool
+	)
+
+	cobraCmd.Flags().StringVar(&fromEnv, "from-env", "", "environment compared, if not present will use --env")
+	cobraCmd.Flags().StringVar(&toEnv, "to-env", "", "environment compared to, if not present will use --env")
+	cobraCmd.Flags().Int64Var(&fromRevision, "from-revision", 0, "revision compared, if not present will use last one")
+	cobraCmd.Flags().Int64Var(&toRevision, "to-revision", 0, "revision compared to, if not present will use last one")
+	cobraCmd.Flags().StringVar(&container, "container", "", "container name, required when task has more than one container and no default one")
+	cobraCmd.Flags().BoolVar(&reveal, "reveal", false, "show values instead of masking them")
+	cobraCmd.Flags().BoolVar(&exitCode, "exit-code", false, "exit with 1 when there are differences")
+
+	cobraCmd.PreRun = func(cobraCmd *cobra.Command, args []string) {
+		cmd.CheckService()
+		cmd.CheckEnvironment()
+	}
+
+	cobraCmd.RunE = func(cobraCmd *cobra.Command, args []string) error {
+		if strings.EqualFold("", fromEnv) {
+			fromEnv = cmd.env
+		}
+		if strings.EqualFold("", toEnv) {
+			toEnv = cmd.env
+		}
+
+		if fromEnv == toEnv && fromRevision == toRevision {
+			return errors.New("command needs two environments (--from-env / --to-env) or two revisions (--from-revision / --to-revision)")
+		}
+
+		containerName := cmd.getContainerName(cmd.ServiceName, container)
+
+		from := cmd.getAWSSession(fromEnv).GetEnvvarSet(cmd.ServiceName, fromRevision, containerName)
+		to := cmd.getAWSSession(toEnv).GetEnvvarSet(cmd.ServiceName, toRevision, containerName)
+
+		if cmd.AWSSession.DiffEnvvarSets(from, to, reveal) && exitCode {
+			os.Exit(1)
+		}
+
+		return nil
+	}
+
+	return cobraCmd
+}
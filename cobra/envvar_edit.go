@@ -0,0 +1,106 @@
+package cobra
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/guilherme-santos/deploy-ecs/aws"
+	"github.com/guilherme-santos/deploy-ecs/shell"
+	"github.com/spf13/cobra"
+)
+
+func newEnvvarEditCommand(cmd *Command) *cobra.Command {
+	cobraCmd := &cobra.Command{
+		Use:   "edit",
+		Short: "Edit env vars in your $EDITOR and register a new revision",
+	}
+
+	var (
+		revision   int64
+		container  string
+		deploy     bool
+		waitDeploy bool
+		reveal     bool
+		force      bool
+	)
+
+	cobraCmd.Flags().Int64Var(&revision, "revision", 0, "revision number, if not present will use last one")
+	cobraCmd.Flags().StringVar(&container, "container", "", "container name, required when task has more than one container and no default one")
+	cobraCmd.Flags().BoolVar(&deploy, "deploy", false, "deploy new revision")
+	cobraCmd.Flags().BoolVar(&waitDeploy, "wait", false, "should be used with --deploy flag")
+	cobraCmd.Flags().BoolVar(&reveal, "reveal", false, "show values of sensitive env vars in the preview instead of masking them")
+	cobraCmd.Flags().BoolVar(&force, "force", false, "register and deploy even when env vars don't match the env schema")
+
+	cobraCmd.PreRun = func(cobraCmd *cobra.Command, args []string) {
+		cmd.CheckService()
+		cmd.CheckEnvironment()
+	}
+
+	cobraCmd.RunE = func(cobraCmd *cobra.Command, args []string) error {
+		containerName := cmd.getContainerName(cmd.ServiceName, container)
+
+		file, err := ioutil.TempFile("", "deploy-ecs-env-*.env")
+		if err != nil {
+			return fmt.Errorf("Cannot create temporary file: %s", err)
+		}
+		defer os.Remove(file.Name())
+
+		file.WriteString(cmd.AWSSession.FormatEnvvarFile(cmd.ServiceName, revision, containerName))
+		file.Close()
+
+		for {
+			if err := shell.OpenEditor(file.Name()); err != nil {
+				return fmt.Errorf("Cannot open editor: %s", err)
+			}
+
+			err := checkEnvvarFile(file.Name())
+			if err == nil {
+				break
+			}
+
+			fmt.Println("Cannot parse env vars:", err)
+			if !shell.Confirm("Edit again?") {
+				return nil
+			}
+		}
+
+		file, err = os.Open(file.Name())
+		if err != nil {
+			return fmt.Errorf("Cannot read temporary file: %s", err)
+		}
+		defer file.Close()
+
+		changes, unsets := cmd.AWSSession.DiffEnvvarFromFile(cmd.ServiceName, revision, containerName, file, aws.EnvFileOptions{})
+
+		if !cmd.AWSSession.PreviewEnvvarChanges(cmd.ServiceName, revision, containerName, changes, unsets, reveal) {
+			fmt.Println("Nothing to update in this task definition")
+			return nil
+		}
+
+		if !shell.Confirm("Register a new revision with these changes?") {
+			fmt.Println("Nothing was changed")
+			return nil
+		}
+
+		newRevision := cmd.AWSSession.UpdateEnvvar(cmd.ServiceName, revision, containerName, changes, unsets, nil, force)
+		if deploy && newRevision > 0 {
+			deployRevision(cmd, cmd.ServiceName, newRevision, waitDeploy, force)
+		}
+
+		return nil
+	}
+
+	return cobraCmd
+}
+
+func checkEnvvarFile(filename string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = aws.ParseDotenv(file, false)
+	return err
+}
@@ -0,0 +1,104 @@
+package cobra
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/guilherme-santos/deploy-ecs/aws"
+	"github.com/guilherme-santos/deploy-ecs/shell"
+	"github.com/spf13/cobra"
+)
+
+func newEnvvarExecCommand(cmd *Command) *cobra.Command {
+	cobraCmd := &cobra.Command{
+		Use:   "exec -- <command> [args...]",
+		Short: "Run a local command with env vars of the deployed revision",
+	}
+
+	var (
+		revision       int64
+		container      string
+		resolveSecrets bool
+		only           []string
+		overrides      []string
+	)
+
+	cobraCmd.Flags().Int64Var(&revision, "revision", 0, "revision number, if not present will use last one")
+	cobraCmd.Flags().StringVar(&container, "container", "", "container name, required when task has more than one container and no default one")
+	cobraCmd.Flags().BoolVar(&resolveSecrets, "resolve-secrets", false, "read secrets from SSM or Secrets Manager, otherwise they're not set")
+	cobraCmd.Flags().StringArrayVar(&only, "only", nil, "use only remote keys matching this pattern, e.g. DATABASE_* (can be used multiple times)")
+	cobraCmd.Flags().StringArrayVar(&overrides, "override", nil, "key=value to be used instead of remote value, e.g. DATABASE_HOST=localhost (can be used multiple times)")
+	// Flags after the command belong to it, e.g. env exec docker run -e X
+	cobraCmd.Flags().SetInterspersed(false)
+
+	cobraCmd.PreRun = func(cobraCmd *cobra.Command, args []string) {
+		cmd.CheckService()
+		cmd.CheckEnvironment()
+	}
+
+	cobraCmd.RunE = func(cobraCmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return errors.New("command needs a command to run: env exec -- <command> [args...]")
+		}
+
+		envvars, secrets := cmd.AWSSession.GetEnvvarValues(cmd.ServiceName, revision, cmd.getContainerName(cmd.ServiceName, container), resolveSecrets)
+
+		envvars, err := aws.FilterEnvvars(envvars, only, nil)
+		if err != nil {
+			return err
+		}
+
+		for _, override := range overrides {
+			parts := strings.SplitN(override, "=", 2)
+			if len(parts) < 2 {
+				return fmt.Errorf("Invalid override '%s', use key=value", override)
+			}
+
+			envvars[parts[0]] = parts[1]
+		}
+
+		// Messages go to stderr, so stdout is only the command output
+		fmt.Fprintf(os.Stderr, "# Running '%s' with %d env vars of '%s'\n", args[0], len(envvars), cmd.ServiceName)
+		if len(secrets) > 0 {
+			fmt.Fprintf(os.Stderr, "# Secrets not set (use --resolve-secrets to read them):\n# %s\n", strings.Join(secrets, "\n# "))
+		}
+
+		exitCode, err := shell.RunWithEnv(args, mergeEnv(os.Environ(), envvars))
+		if err != nil {
+			return fmt.Errorf("Cannot run '%s': %s", args[0], err)
+		}
+
+		os.Exit(exitCode)
+		return nil
+	}
+
+	return cobraCmd
+}
+
+// mergeEnv returns environ (as KEY=VALUE) with envvars replacing local
+// variables of same name.
+func mergeEnv(environ []string, envvars map[string]string) []string {
+	merged := make([]string, 0, len(environ)+len(envvars))
+
+	for _, kv := range environ {
+		key := strings.SplitN(kv, "=", 2)[0]
+		if _, ok := envvars[key]; !ok {
+			merged = append(merged, kv)
+		}
+	}
+
+	keys := make([]string, 0, len(envvars))
+	for key := range envvars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		merged = append(merged, key+"="+envvars[key])
+	}
+
+	return merged
+}
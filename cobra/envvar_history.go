@@ -0,0 +1,49 @@
+package cobra
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+)
+
+func newEnvvarHistoryCommand(cmd *Command) *cobra.Command {
+	cobraCmd := &cobra.Command{
+		Use:   "history [KEY]",
+		Short: "Show how an env var (or all of them) changed over the revisions",
+	}
+
+	var (
+		container    string
+		limit        int64
+		showInactive bool
+		reveal       bool
+	)
+
+	cobraCmd.Flags().StringVar(&container, "container", "", "container name, required when task has more than one container and no default one")
+	cobraCmd.Flags().Int64Var(&limit, "limit", 50, "number of revisions to walk, 0 means all")
+	cobraCmd.Flags().BoolVar(&showInactive, "inactive", false, "include deregistered revisions")
+	cobraCmd.Flags().BoolVar(&reveal, "reveal", false, "show values of sensitive env vars instead of masking them")
+
+	cobraCmd.PreRun = func(cobraCmd *cobra.Command, args []string) {
+		cmd.CheckService()
+		cmd.CheckEnvironment()
+	}
+
+	cobraCmd.RunE = func(cobraCmd *cobra.Command, args []string) error {
+		if len(args) > 1 {
+			return errors.New("command accepts only one KEY")
+		}
+
+		containerName := cmd.getContainerName(cmd.ServiceName, container)
+
+		if len(args) == 1 {
+			cmd.AWSSession.EnvvarHistory(cmd.ServiceName, containerName, args[0], limit, showInactive, reveal)
+		} else {
+			cmd.AWSSession.EnvvarsHistory(cmd.ServiceName, containerName, limit, showInactive, reveal)
+		}
+
+		return nil
+	}
+
+	return cobraCmd
+}
@@ -0,0 +1,78 @@
+package cobra
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+func NewLabelsCommand(cmd *Command) {
+	cobraCmd := &cobra.Command{
+		Use:   "labels",
+		Short: "Manage labels (e.g. stable, last-good) pointing to revisions",
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List labels of a service",
+		Run: func(cobraCmd *cobra.Command, args []string) {
+			cmd.AWSSession.PrintLabels(cmd.ServiceName)
+		},
+	}
+
+	setCmd := &cobra.Command{
+		Use:   "set <label> <revision>",
+		Short: "Create a label pointing to a revision",
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return setLabel(cmd, args, false)
+		},
+	}
+
+	moveCmd := &cobra.Command{
+		Use:   "move <label> <revision>",
+		Short: "Point an existing label to another revision",
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			return setLabel(cmd, args, true)
+		},
+	}
+
+	deleteCmd := &cobra.Command{
+		Use:   "delete <label>",
+		Short: "Remove a label",
+		RunE: func(cobraCmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("command needs an argument: <label>")
+			}
+
+			cmd.AWSSession.DeleteLabel(cmd.ServiceName, args[0])
+			return nil
+		},
+	}
+
+	for _, subCmd := range []*cobra.Command{listCmd, setCmd, moveCmd, deleteCmd} {
+		subCmd.PreRun = func(cobraCmd *cobra.Command, args []string) {
+			cmd.CheckService()
+			cmd.CheckEnvironment()
+		}
+
+		cobraCmd.AddCommand(subCmd)
+	}
+
+	cmd.AddCommand(cobraCmd)
+}
+
+func setLabel(cmd *Command, args []string, move bool) error {
+	if len(args) < 2 {
+		return errors.New("command needs two arguments: <label> <revision>")
+	}
+
+	revision, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil || revision <= 0 {
+		return fmt.Errorf("Invalid revision '%s'", args[1])
+	}
+
+	cmd.AWSSession.SetLabel(cmd.ServiceName, args[0], revision, move)
+	return nil
+}
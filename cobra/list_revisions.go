@@ -10,13 +10,21 @@ func NewListRevisionsCommand(cmd *Command) {
 		Short: "List all availables revision from a service",
 	}
 
+	var (
+		limit        int64
+		showInactive bool
+	)
+
+	cobraCmd.Flags().Int64Var(&limit, "limit", 10, "number of revisions to be listed, 0 lists all of them")
+	cobraCmd.Flags().BoolVar(&showInactive, "inactive", false, "list also deregistered (INACTIVE) revisions")
+
 	cobraCmd.PreRun = func(cobraCmd *cobra.Command, args []string) {
 		cmd.CheckService()
 		cmd.CheckEnvironment()
 	}
 
 	cobraCmd.Run = func(cobraCmd *cobra.Command, args []string) {
-		cmd.AWSSession.ListRevisions(cmd.ServiceName)
+		cmd.AWSSession.ListRevisions(cmd.ServiceName, limit, showInactive)
 	}
 
 	cmd.AddCommand(cobraCmd)
@@ -0,0 +1,127 @@
+package cobra
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+func NewRevisionsCommand(cmd *Command) {
+	cobraCmd := &cobra.Command{
+		Use:   "revisions",
+		Short: "Manage revisions of a task definition",
+	}
+
+	cobraCmd.AddCommand(newRevisionsPruneCommand(cmd))
+	cobraCmd.AddCommand(newRevisionsRestoreCommand(cmd))
+	cobraCmd.AddCommand(newRevisionsPinCommand(cmd, "pin", "Protect a revision of being deregistered by prune", true))
+	cobraCmd.AddCommand(newRevisionsPinCommand(cmd, "unpin", "Allow prune to deregister a revision again", false))
+
+	cmd.AddCommand(cobraCmd)
+}
+
+func parseRevision(args []string) (int64, error) {
+	if len(args) == 0 {
+		return 0, errors.New("command needs an argument: <revision>")
+	}
+
+	revision, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil || revision <= 0 {
+		return 0, fmt.Errorf("Invalid revision '%s'", args[0])
+	}
+
+	return revision, nil
+}
+
+func newRevisionsPruneCommand(cmd *Command) *cobra.Command {
+	cobraCmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Deregister old revisions, except the ones running or pinned",
+	}
+
+	var (
+		keep   int
+		dryRun bool
+	)
+
+	cobraCmd.Flags().IntVar(&keep, "keep", 10, "number of last revisions to be kept")
+	cobraCmd.Flags().BoolVar(&dryRun, "dry-run", false, "only list revisions which would be deregistered")
+
+	cobraCmd.PreRun = func(cobraCmd *cobra.Command, args []string) {
+		cmd.CheckService()
+		cmd.CheckEnvironment()
+	}
+
+	cobraCmd.RunE = func(cobraCmd *cobra.Command, args []string) error {
+		if keep < 1 {
+			return errors.New("--keep needs to be at least 1")
+		}
+
+		cmd.AWSSession.PruneRevisions(cmd.ServiceName, keep, dryRun)
+		return nil
+	}
+
+	return cobraCmd
+}
+
+func newRevisionsRestoreCommand(cmd *Command) *cobra.Command {
+	cobraCmd := &cobra.Command{
+		Use:   "restore <revision>",
+		Short: "Register a deregistered revision again, as a new revision",
+	}
+
+	var (
+		deploy     bool
+		waitDeploy bool
+	)
+
+	cobraCmd.Flags().BoolVar(&deploy, "deploy", false, "deploy restored revision")
+	cobraCmd.Flags().BoolVar(&waitDeploy, "wait", false, "should be used with --deploy flag")
+
+	cobraCmd.PreRun = func(cobraCmd *cobra.Command, args []string) {
+		cmd.CheckService()
+		cmd.CheckEnvironment()
+	}
+
+	cobraCmd.RunE = func(cobraCmd *cobra.Command, args []string) error {
+		revision, err := parseRevision(args)
+		if err != nil {
+			return err
+		}
+
+		arn := cmd.AWSSession.RestoreRevision(cmd.ServiceName, revision)
+		if deploy {
+			deployTaskDefinition(cmd, cmd.ServiceName, arn, waitDeploy)
+		}
+
+		return nil
+	}
+
+	return cobraCmd
+}
+
+func newRevisionsPinCommand(cmd *Command, use, short string, pin bool) *cobra.Command {
+	cobraCmd := &cobra.Command{
+		Use:   use + " <revision>",
+		Short: short,
+	}
+
+	cobraCmd.PreRun = func(cobraCmd *cobra.Command, args []string) {
+		cmd.CheckService()
+		cmd.CheckEnvironment()
+	}
+
+	cobraCmd.RunE = func(cobraCmd *cobra.Command, args []string) error {
+		revision, err := parseRevision(args)
+		if err != nil {
+			return err
+		}
+
+		cmd.AWSSession.PinRevision(cmd.ServiceName, revision, pin)
+		return nil
+	}
+
+	return cobraCmd
+}
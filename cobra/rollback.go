@@ -1,6 +1,11 @@
 package cobra
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
 	"github.com/spf13/cobra"
 )
 
@@ -10,8 +15,14 @@ func NewRollbackCommand(cmd *Command) {
 		Short: "Rollback service to last revision",
 	}
 
-	var wait bool
+	var (
+		toLabel    string
+		toRevision string
+		wait       bool
+	)
 
+	cobraCmd.Flags().StringVar(&toLabel, "to-label", "", "rollback every service to revision with this label instead of the previous one")
+	cobraCmd.Flags().StringVar(&toRevision, "to-revision", "", "rollback only --service to this revision number or label")
 	cobraCmd.Flags().BoolVar(&wait, "wait", false, "wait until service are stable")
 
 	cobraCmd.PreRun = func(cobraCmd *cobra.Command, args []string) {
@@ -19,17 +30,59 @@ func NewRollbackCommand(cmd *Command) {
 		cmd.CheckEnvironment()
 	}
 
-	cobraCmd.Run = func(cobraCmd *cobra.Command, args []string) {
+	cobraCmd.RunE = func(cobraCmd *cobra.Command, args []string) error {
+		if !strings.EqualFold("", toLabel) && !strings.EqualFold("", toRevision) {
+			return errors.New("command needs just one argument: --to-label OR --to-revision")
+		}
+
+		if !strings.EqualFold("", toRevision) {
+			arn := cmd.AWSSession.ResolveTaskDefinitionArn(cmd.ServiceName, toRevision)
+			doDeploy(cmd, map[string]string{cmd.ServiceName: arn}, wait)
+			return nil
+		}
+
 		services := cmd.AWSSession.ListTaskDefinitionStartedWith(cmd.ServiceName)
 
-		for _, service := range services {
-			cmd.AWSSession.Rollback(service)
+		if strings.EqualFold("", toLabel) {
+			for _, service := range services {
+				cmd.AWSSession.Rollback(service)
+			}
+		} else {
+			rollbackToLabel(cmd, services, toLabel)
 		}
 
 		if wait && len(services) > 0 {
 			cmd.AWSSession.WaitUntilServicesStable(services)
 		}
+
+		return nil
 	}
 
 	cmd.AddCommand(cobraCmd)
 }
+
+// rollbackToLabel deploys the labeled revision of every service, nothing is
+// deployed when one of them doesn't have the label.
+func rollbackToLabel(cmd *Command, services []string, label string) {
+	taskDefinitions := make(map[string]string)
+	missing := make([]string, 0)
+
+	for _, service := range services {
+		current := cmd.AWSSession.GetLabel(service, label)
+		if current == nil {
+			missing = append(missing, service)
+			continue
+		}
+
+		taskDefinitions[service] = cmd.AWSSession.ResolveTaskDefinitionArn(service, fmt.Sprint(current.Revision))
+	}
+
+	if len(missing) > 0 {
+		fmt.Printf("Label '%s' was not found to:\n  - %s\n", label, strings.Join(missing, "\n  - "))
+		os.Exit(1)
+	}
+
+	for _, service := range services {
+		cmd.AWSSession.Deploy(service, taskDefinitions[service])
+	}
+}
@@ -8,6 +8,7 @@ import (
 	deploy "github.com/guilherme-santos/deploy-ecs"
 	"github.com/guilherme-santos/deploy-ecs/aws"
 	"github.com/guilherme-santos/deploy-ecs/shell"
+	"github.com/guilherme-santos/deploy-ecs/ssh"
 	"github.com/spf13/cobra"
 )
 
@@ -53,6 +54,11 @@ func NewCommand(version, build string) *Command {
 		}
 	}
 
+	cmd.PersistentPostRun = func(cobraCmd *cobra.Command, args []string) {
+		// Connections are shared between all steps of a command
+		ssh.CloseConnections()
+	}
+
 	cmd.Flags().BoolVarP(&versionFlag, "version", "v", false, "Print version information")
 
 	cmd.PersistentFlags().StringVarP(&cmd.Service.Name, "service", "s", "", `Service name, some commands will affect <service-name>-*
@@ -70,6 +76,9 @@ func NewCommand(version, build string) *Command {
 	NewConfigCommand(cmd)
 	// NewServicesCommand(cmd)
 	NewListRevisionsCommand(cmd)
+	NewDiffRevisionsCommand(cmd)
+	NewRevisionsCommand(cmd)
+	NewLabelsCommand(cmd)
 	NewTaskDefinitionCommand(cmd)
 	NewEnvvarCommand(cmd)
 	NewProcessStatusCommand(cmd)
@@ -79,6 +88,7 @@ func NewCommand(version, build string) *Command {
 	NewExecCommand(cmd)
 	NewKillCommand(cmd)
 	NewScaleCommand(cmd)
+	NewCacheCommand(cmd)
 
 	return cmd
 }
@@ -100,13 +110,47 @@ func (cmd *Command) getServiceNameWithNamespace() string {
 	return cmd.Service.Name
 }
 
+// getContainerName returns container when informed, otherwise the default
+// container configured to service.
+func (cmd *Command) getContainerName(service, container string) string {
+	if !strings.EqualFold("", container) {
+		return container
+	}
+
+	return cmd.Config.GetDefaultContainer(service)
+}
+
 func (cmd *Command) CheckEnvironment() {
 	cmd.Environment = cmd.Config.GetEnvironment(cmd.env)
 	if cmd.Environment == nil {
 		fmt.Printf("Environment '%s' is not a valid, use: %s\n", cmd.env, cmd.getListEnvironments())
 		os.Exit(1)
 	}
-	cmd.AWSSession = aws.NewAWSSession(cmd.Environment)
+	cmd.AWSSession = cmd.newAWSSession(cmd.Environment)
+}
+
+// newAWSSession returns a session to env using preferences of config file.
+func (cmd *Command) newAWSSession(env *deploy.Environment) *aws.AWSSession {
+	sess := aws.NewAWSSession(env)
+	sess.Masker = aws.NewMasker(cmd.Config.Masking.Patterns, cmd.Config.Masking.DetectEntropy)
+
+	return sess
+}
+
+// getAWSSession returns a session to another environment, e.g. to compare it
+// with the current one. Each environment can be in a different region.
+func (cmd *Command) getAWSSession(environment string) *aws.AWSSession {
+	if strings.EqualFold("", environment) || environment == cmd.env {
+		return cmd.AWSSession
+	}
+
+	env := cmd.Config.GetEnvironment(environment)
+	if env == nil {
+		fmt.Printf("Environment '%s' is not a valid, use: %s\n", environment, cmd.getListEnvironments())
+		os.Exit(1)
+	}
+
+	return cmd.newAWSSession(env)
 }
 
 func (cmd *Command) CheckService() {
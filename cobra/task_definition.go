@@ -1,10 +1,16 @@
 package cobra
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/guilherme-santos/deploy-ecs/aws"
+	"github.com/guilherme-santos/deploy-ecs/shell"
 	"github.com/spf13/cobra"
 )
 
@@ -16,12 +22,14 @@ func NewTaskDefinitionCommand(cmd *Command) {
 
 	var (
 		revision   int64
+		container  string
 		sets       []string
 		deploy     bool
 		waitDeploy bool
 	)
 
 	cobraCmd.Flags().Int64Var(&revision, "revision", 0, "revision number, if not present will use last one")
+	cobraCmd.Flags().StringVar(&container, "container", "", "container name, required when task has more than one container and no default one")
 	cobraCmd.Flags().StringArrayVar(&sets, "set", nil, "property=value to be updated (can be used multiple times)")
 	cobraCmd.Flags().BoolVar(&deploy, "deploy", false, "change task-definition and redeploy")
 	cobraCmd.Flags().BoolVar(&waitDeploy, "wait", false, "should be used with --deploy flag")
@@ -37,17 +45,166 @@ func NewTaskDefinitionCommand(cmd *Command) {
 			return
 		}
 
-		updateTaskDefinition(cmd, revision, sets, deploy, waitDeploy)
+		updateTaskDefinition(cmd, revision, cmd.getContainerName(cmd.ServiceName, container), sets, deploy, waitDeploy)
 	}
 
+	cobraCmd.AddCommand(newTaskDefinitionExportCommand(cmd))
+	cobraCmd.AddCommand(newTaskDefinitionApplyCommand(cmd))
+	cobraCmd.AddCommand(newTaskDefinitionImportCommand(cmd))
+	cobraCmd.AddCommand(newTaskDefinitionRenderCommand(cmd))
+	cobraCmd.AddCommand(newDiffRevisionsCommand(cmd, "diff"))
+
 	cmd.AddCommand(cobraCmd)
 }
 
+func newTaskDefinitionExportCommand(cmd *Command) *cobra.Command {
+	cobraCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export task definition as a file accepted by apply, or as a docker-compose.yml",
+	}
+
+	var (
+		revision int64
+		output   string
+		format   string
+	)
+
+	cobraCmd.Flags().Int64Var(&revision, "revision", 0, "revision number, if not present will use last one")
+	cobraCmd.Flags().StringVarP(&output, "output", "o", "", "file to be written, if not present will use stdout")
+	cobraCmd.Flags().StringVar(&format, "format", "", "json, yaml or compose, if not present will use name of --output or json")
+
+	cobraCmd.PreRun = func(cobraCmd *cobra.Command, args []string) {
+		cmd.CheckService()
+		cmd.CheckEnvironment()
+	}
+
+	cobraCmd.Run = func(cobraCmd *cobra.Command, args []string) {
+		cmd.AWSSession.ExportTaskDefinition(cmd.ServiceName, revision, output, format)
+	}
+
+	return cobraCmd
+}
+
+func newTaskDefinitionApplyCommand(cmd *Command) *cobra.Command {
+	cobraCmd := &cobra.Command{
+		Use:   "apply -f <file>",
+		Short: "Register a new revision from a file when it's different from the last one",
+	}
+
+	var (
+		filename   string
+		format     string
+		values     string
+		isTemplate bool
+		deploy     bool
+		waitDeploy bool
+		yes        bool
+		reveal     bool
+	)
+
+	cobraCmd.Flags().StringVarP(&filename, "file", "f", "", "file with the task definition, use - to read from stdin")
+	cobraCmd.Flags().StringVar(&format, "format", "", "json or yaml, if not present will use extension of --file or json")
+	cobraCmd.Flags().StringVar(&values, "values", "", "values used by template, if not present will use values/<env>.yaml next to it")
+	cobraCmd.Flags().BoolVar(&isTemplate, "template", false, "render file as template, default when --file ends with .tmpl or .tpl")
+	cobraCmd.Flags().BoolVar(&deploy, "deploy", false, "deploy new revision")
+	cobraCmd.Flags().BoolVar(&waitDeploy, "wait", false, "should be used with --deploy flag")
+	cobraCmd.Flags().BoolVarP(&yes, "yes", "y", false, "don't ask for confirmation")
+	cobraCmd.Flags().BoolVar(&reveal, "reveal", false, "show values of sensitive env vars in the preview instead of masking them")
+
+	cobraCmd.PreRun = func(cobraCmd *cobra.Command, args []string) {
+		cmd.CheckService()
+		cmd.CheckEnvironment()
+	}
+
+	cobraCmd.RunE = func(cobraCmd *cobra.Command, args []string) error {
+		if strings.EqualFold("", filename) {
+			return errors.New("command needs an argument: -f <file>")
+		}
+
+		var input *ecs.RegisterTaskDefinitionInput
+		if isTemplate || aws.IsTemplateFile(filename) {
+			input = cmd.AWSSession.ReadTaskDefinitionTemplate(filename, values, format, cmd.ServiceName)
+		} else {
+			input = aws.ReadTaskDefinitionFile(filename, format)
+		}
+
+		if !cmd.AWSSession.PreviewTaskDefinition(cmd.ServiceName, input, reveal) {
+			return nil
+		}
+
+		if !yes && !shell.Confirm(fmt.Sprintf("Register a new revision of '%s'?", *input.Family)) {
+			fmt.Println("Nothing was changed")
+			return nil
+		}
+
+		arn := cmd.AWSSession.RegisterTaskDefinition(input)
+		if deploy {
+			deployTaskDefinition(cmd, *input.Family, arn, waitDeploy)
+		}
+
+		return nil
+	}
+
+	return cobraCmd
+}
+
+func newTaskDefinitionImportCommand(cmd *Command) *cobra.Command {
+	cobraCmd := &cobra.Command{
+		Use:   "import <compose-file>",
+		Short: "Register a task definition from the services of a docker-compose.yml",
+	}
+
+	var (
+		family      string
+		networkMode string
+		dryRun      bool
+		yes         bool
+		reveal      bool
+	)
+
+	cobraCmd.Flags().StringVar(&family, "family", "", "family of the task definition, if not present will use --service")
+	cobraCmd.Flags().StringVar(&networkMode, "network-mode", ecs.NetworkModeAwsvpc, "network mode of the task: awsvpc, bridge, host or none")
+	cobraCmd.Flags().BoolVar(&dryRun, "dry-run", false, "only show the task definition which would be registered")
+	cobraCmd.Flags().BoolVarP(&yes, "yes", "y", false, "don't ask for confirmation")
+	cobraCmd.Flags().BoolVar(&reveal, "reveal", false, "show values of sensitive env vars in the preview instead of masking them")
+
+	cobraCmd.PreRun = func(cobraCmd *cobra.Command, args []string) {
+		cmd.CheckService()
+		cmd.CheckEnvironment()
+	}
+
+	cobraCmd.RunE = func(cobraCmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errors.New("command needs an argument: import <compose-file>")
+		}
+		if strings.EqualFold("", family) {
+			family = cmd.ServiceName
+		}
+
+		compose := aws.ReadComposeFile(args[0])
+		input, warnings := aws.NewComposeTaskDefinitionInput(compose, family, networkMode, filepath.Dir(args[0]))
+
+		if !cmd.AWSSession.PreviewImport(input, warnings, reveal) || dryRun {
+			return nil
+		}
+
+		if !yes && !shell.Confirm(fmt.Sprintf("Register a new revision of '%s'?", family)) {
+			fmt.Println("Nothing was changed")
+			return nil
+		}
+
+		cmd.AWSSession.RegisterTaskDefinition(input)
+		return nil
+	}
+
+	return cobraCmd
+}
+
 func getTaskDefinition(cmd *Command, revision int64) {
 	cmd.AWSSession.GetTaskDefinition(cmd.ServiceName, revision)
 }
 
-func updateTaskDefinition(cmd *Command, revision int64, sets []string, deploy, waitDeploy bool) {
+func updateTaskDefinition(cmd *Command, revision int64, container string, sets []string, deploy, waitDeploy bool) {
 	changes := make(map[string]string)
 	for _, change := range sets {
 		parts := strings.SplitN(change, "=", 2)
@@ -60,19 +217,74 @@ func updateTaskDefinition(cmd *Command, revision int64, sets []string, deploy, w
 		changes[parts[0]] = value
 	}
 
-	arn := cmd.AWSSession.UpdateTaskDefinition(cmd.ServiceName, revision, changes)
+	arn := cmd.AWSSession.UpdateTaskDefinition(cmd.ServiceName, revision, container, changes)
 	if deploy {
-		revision := arn[strings.LastIndex(arn, ":")+1:]
+		deployTaskDefinition(cmd, cmd.ServiceName, arn, waitDeploy)
+	}
+}
 
-		deployArgs := []string{"deploy", "--service", cmd.ServiceName, "--revision", fmt.Sprint(revision)}
-		if waitDeploy {
-			deployArgs = append(deployArgs, "--wait")
-		}
+func newTaskDefinitionRenderCommand(cmd *Command) *cobra.Command {
+	cobraCmd := &cobra.Command{
+		Use:   "render -f <template>",
+		Short: "Show task definition template rendered to an environment without registering it",
+	}
 
-		cmd.SetArgs(deployArgs)
-		if err := cmd.Execute(); err != nil {
-			fmt.Printf("Cannot deploy new revision to '%s': %s", cmd.ServiceName, err.Error())
-			os.Exit(1)
+	var (
+		filename string
+		format   string
+		values   string
+	)
+
+	cobraCmd.Flags().StringVarP(&filename, "file", "f", "", "template of the task definition, use - to read from stdin")
+	cobraCmd.Flags().StringVar(&format, "format", "", "json or yaml, if not present will use extension of --file or json")
+	cobraCmd.Flags().StringVar(&values, "values", "", "values used by template, if not present will use values/<env>.yaml next to it")
+
+	cobraCmd.PreRun = func(cobraCmd *cobra.Command, args []string) {
+		cmd.CheckService()
+		cmd.CheckEnvironment()
+	}
+
+	cobraCmd.RunE = func(cobraCmd *cobra.Command, args []string) error {
+		if strings.EqualFold("", filename) {
+			return errors.New("command needs an argument: -f <template>")
 		}
+
+		content := aws.RenderTaskDefinitionTemplate(filename, values, cmd.ServiceName, cmd.Environment)
+
+		// Make sure the result can be applied
+		aws.DecodeTaskDefinition(content, aws.GetFileFormat(filename, format))
+
+		os.Stdout.Write(content)
+		return nil
+	}
+
+	return cobraCmd
+}
+
+func deployTaskDefinition(cmd *Command, service, arn string, waitDeploy bool) {
+	revision, _ := strconv.ParseInt(arn[strings.LastIndex(arn, ":")+1:], 10, 64)
+	deployRevision(cmd, service, revision, waitDeploy, false)
+}
+
+// deployRevision runs deploy command to revision of service, force skips the
+// env schema validation.
+func deployRevision(cmd *Command, service string, revision int64, waitDeploy, force bool) {
+	deployRevisionToEnv(cmd, cmd.env, service, revision, waitDeploy, force)
+}
+
+func deployRevisionToEnv(cmd *Command, env, service string, revision int64, waitDeploy, force bool) {
+	// service already has the namespace
+	deployArgs := []string{"deploy", "--env", env, "--service", service, "--namespace=", "--revision", fmt.Sprint(revision)}
+	if waitDeploy {
+		deployArgs = append(deployArgs, "--wait")
+	}
+	if force {
+		deployArgs = append(deployArgs, "--force")
+	}
+
+	cmd.SetArgs(deployArgs)
+	if err := cmd.Execute(); err != nil {
+		fmt.Printf("Cannot deploy new revision to '%s': %s", service, err.Error())
+		os.Exit(1)
 	}
 }
@@ -1,18 +1,30 @@
 package deploy
 
-import "strings"
+import (
+	"net"
+	"strings"
+)
 
 type (
 	Config struct {
 		DefaultEnvironment string
 		Environments       []Environment
+		Services           []ServiceConfig
 		GitHub             GitHubConfig
+		Masking            MaskingConfig
+	}
+
+	// ServiceConfig keeps preferences of a task definition family.
+	ServiceConfig struct {
+		Name             string
+		DefaultContainer string
 	}
 
 	Environment struct {
 		ClusterName string
 		Region      string
 		Bastion     ServerConfig
+		JumpHosts   []ServerConfig
 		ECSHost     ServerConfig
 	}
 
@@ -23,6 +35,13 @@ type (
 		KeyPair string
 	}
 
+	// MaskingConfig tells which env vars are masked in outputs, nil Patterns
+	// means the default ones.
+	MaskingConfig struct {
+		Patterns      []string
+		DetectEntropy bool
+	}
+
 	GitHubConfig struct {
 		Token             string
 		DefaultRepository string
@@ -38,6 +57,76 @@ func (env *Environment) HasBastion() bool {
 	return !strings.EqualFold("", env.Bastion.Host)
 }
 
+// GetJumpHosts returns every hop needed to reach the ECS machines, the
+// bastion (when configured) is always the first one.
+func (env *Environment) GetJumpHosts() []ServerConfig {
+	jumpHosts := make([]ServerConfig, 0, len(env.JumpHosts)+1)
+	if env.HasBastion() {
+		jumpHosts = append(jumpHosts, env.Bastion)
+	}
+
+	return append(jumpHosts, env.JumpHosts...)
+}
+
+func (server ServerConfig) String() string {
+	var result string
+
+	if !strings.EqualFold("", server.User) {
+		result += server.User + "@"
+	}
+	if !strings.EqualFold("", server.Port) {
+		result += net.JoinHostPort(server.Host, server.Port)
+	} else {
+		result += server.Host
+	}
+
+	return result
+}
+
+// ParseProxyJump reads a chain in the same format used by OpenSSH ProxyJump:
+// [user@]host[:port] separated by comma.
+func ParseProxyJump(proxyJump string) []ServerConfig {
+	servers := make([]ServerConfig, 0)
+
+	for _, hop := range strings.Split(proxyJump, ",") {
+		hop = strings.TrimSpace(hop)
+		hop = strings.TrimPrefix(hop, "ssh://")
+		if strings.EqualFold("", hop) {
+			continue
+		}
+
+		var server ServerConfig
+
+		if pos := strings.LastIndex(hop, "@"); pos >= 0 {
+			server.User = hop[:pos]
+			hop = hop[pos+1:]
+		}
+
+		host, port, err := net.SplitHostPort(hop)
+		if err != nil {
+			host = hop
+			port = ""
+		}
+
+		server.Host = host
+		server.Port = port
+
+		servers = append(servers, server)
+	}
+
+	return servers
+}
+
+// FormatProxyJump is the opposite of ParseProxyJump.
+func FormatProxyJump(servers []ServerConfig) string {
+	hops := make([]string, len(servers))
+	for k, server := range servers {
+		hops[k] = server.String()
+	}
+
+	return strings.Join(hops, ",")
+}
+
 func (config *Config) GetAvailableEnvironments() []string {
 	environments := make([]string, len(config.Environments))
 
@@ -57,3 +146,23 @@ func (config *Config) GetEnvironment(environment string) *Environment {
 
 	return nil
 }
+
+func (config *Config) GetService(service string) *ServiceConfig {
+	for k, svc := range config.Services {
+		if strings.EqualFold(service, svc.Name) {
+			return &config.Services[k]
+		}
+	}
+
+	return nil
+}
+
+// GetDefaultContainer returns the container changed when none is informed, it
+// returns empty when the service has no default one.
+func (config *Config) GetDefaultContainer(service string) string {
+	if svc := config.GetService(service); svc != nil {
+		return svc.DefaultContainer
+	}
+
+	return ""
+}
@@ -7,7 +7,9 @@ import (
 	"net"
 	"os"
 	"os/exec"
+	"os/signal"
 	"strings"
+	"syscall"
 )
 
 func GetGoCommand(command string) *exec.Cmd {
@@ -99,3 +101,88 @@ func CreateTempDir(prefix string) string {
 func RemoveTempDir(dir string) {
 	os.RemoveAll(dir)
 }
+
+// OpenEditor opens filename in $VISUAL or $EDITOR (vi when both are empty)
+// and waits until it's closed.
+func OpenEditor(filename string) error {
+	editor := os.Getenv("VISUAL")
+	if strings.EqualFold("", editor) {
+		editor = os.Getenv("EDITOR")
+	}
+	if strings.EqualFold("", editor) {
+		editor = "vi"
+	}
+
+	// Editors like "code --wait" need their arguments
+	args := append(strings.Fields(editor), filename)
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	return cmd.Run()
+}
+
+// Confirm asks a yes/no question, anything different of y or yes is no.
+func Confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+
+	var answer string
+	fmt.Scanln(&answer)
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// RunWithEnv runs args attached to the terminal with env as its environment
+// and returns its exit code, 128+signal when it was killed by a signal.
+// Signals sent to deploy-ecs are forwarded to it while it runs.
+func RunWithEnv(args []string, env []string) (int, error) {
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Env = env
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	// Ctrl+C and Ctrl+\ already reach the command by the terminal, they're
+	// only dropped here. signal.Ignore is not used since the command would
+	// inherit it. SIGTERM and SIGHUP are sent just to deploy-ecs
+	terminal := make(chan os.Signal, 1)
+	signal.Notify(terminal, os.Interrupt, syscall.SIGQUIT)
+	defer signal.Stop(terminal)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				cmd.Process.Signal(sig)
+			case <-terminal:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err := cmd.Wait()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return 128 + int(status.Signal()), nil
+		}
+
+		return exitErr.ExitCode(), nil
+	}
+
+	return 0, err
+}
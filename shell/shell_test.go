@@ -0,0 +1,92 @@
+package shell
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestRunWithEnvExitCode(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   int
+	}{
+		{name: "success", script: "exit 0", want: 0},
+		{name: "exit code", script: "exit 3", want: 3},
+		{name: "killed by SIGTERM", script: "kill -TERM $$", want: 128 + int(syscall.SIGTERM)},
+		{name: "killed by SIGKILL", script: "kill -KILL $$", want: 128 + int(syscall.SIGKILL)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := RunWithEnv([]string{"sh", "-c", test.script}, os.Environ())
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got != test.want {
+				t.Errorf("got %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+// readIgnoredSignals returns SigIgn of /proc/<pid>/status written by grep.
+func readIgnoredSignals(t *testing.T, content string) uint64 {
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "SigIgn:") {
+			continue
+		}
+
+		mask, err := strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(line, "SigIgn:")), 16, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return mask
+	}
+
+	t.Fatalf("SigIgn not found in %q", content)
+	return 0
+}
+
+func TestRunWithEnvKeepsSignalsOfCommand(t *testing.T) {
+	status, err := ioutil.ReadFile("/proc/self/status")
+	if err != nil {
+		t.Skip("needs /proc to read ignored signals")
+	}
+
+	// Signals ignored by whoever started the test are inherited anyway
+	inherited := readIgnoredSignals(t, string(status))
+
+	output, err := ioutil.TempFile("", "deploy-ecs-shell")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(output.Name())
+	defer output.Close()
+
+	stdout := os.Stdout
+	os.Stdout = output
+	_, err = RunWithEnv([]string{"grep", "SigIgn", "/proc/self/status"}, os.Environ())
+	os.Stdout = stdout
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	content, _ := ioutil.ReadFile(output.Name())
+	ignored := readIgnoredSignals(t, string(content))
+
+	for _, sig := range []syscall.Signal{syscall.SIGINT, syscall.SIGQUIT} {
+		bit := uint64(1) << uint(sig-1)
+		if ignored&bit != 0 && inherited&bit == 0 {
+			t.Errorf("%s is ignored by the command", sig)
+		}
+	}
+}
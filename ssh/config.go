@@ -20,6 +20,7 @@ var DefaultSSHPort = "22"
 
 type SSHConfig struct {
 	server      deploy.ServerConfig
+	hostConfig  HostConfig
 	client      *ssh.Client
 	currentUser string
 	homeDir     string
@@ -34,28 +35,25 @@ func NewLocalSSHConfig(server deploy.ServerConfig) *SSHConfig {
 
 	return &SSHConfig{
 		server:      server,
+		hostConfig:  GetUserConfig().Lookup(server.Host),
 		currentUser: currentUser.Username,
 		homeDir:     currentUser.HomeDir,
 	}
 }
 
+// NewRemoteSSHConfig is used to connect to server through client, besides
+// the local keys it also tries the ones available on the previous hop.
 func NewRemoteSSHConfig(client *ssh.Client, server deploy.ServerConfig) *SSHConfig {
-	config := &SSHConfig{
-		server:      server,
-		client:      client,
-		currentUser: server.User,
-	}
-
-	config.homeDir, _ = config.runCommand("echo $HOME")
+	config := NewLocalSSHConfig(server)
+	config.client = client
 
 	return config
 }
 
-func (config *SSHConfig) runCommand(command string) (string, error) {
-	sess, err := config.client.NewSession()
+func runCommand(client *ssh.Client, command string) (string, error) {
+	sess, err := client.NewSession()
 	if err != nil {
-		fmt.Println("Cannot get session:", err)
-		os.Exit(1)
+		return "", err
 	}
 
 	defer sess.Close()
@@ -81,11 +79,17 @@ func (config *SSHConfig) GetUser() string {
 	if !strings.EqualFold("", config.server.User) {
 		return config.server.User
 	}
+	if !strings.EqualFold("", config.hostConfig.User) {
+		return config.hostConfig.User
+	}
 
 	return config.currentUser
 }
 
 func (config *SSHConfig) GetHost() string {
+	if !strings.EqualFold("", config.hostConfig.HostName) {
+		return config.hostConfig.HostName
+	}
 	if !strings.EqualFold("", config.server.Host) {
 		return config.server.Host
 	}
@@ -97,56 +101,53 @@ func (config *SSHConfig) GetPort() string {
 	if !strings.EqualFold("", config.server.Port) {
 		return config.server.Port
 	}
+	if !strings.EqualFold("", config.hostConfig.Port) {
+		return config.hostConfig.Port
+	}
 
 	return DefaultSSHPort
 }
 
 func (config *SSHConfig) GetURL() string {
-	return config.GetHost() + ":" + config.GetPort()
+	return net.JoinHostPort(config.GetHost(), config.GetPort())
 }
 
-func (config *SSHConfig) GetAuthMethods() []ssh.AuthMethod {
-	authMethods := make([]ssh.AuthMethod, 0, 5)
-
-	if config.client == nil {
-		// It's a local connection
-		sshAgentSock := os.Getenv("SSH_AUTH_SOCK")
-		if !strings.EqualFold("", sshAgentSock) {
-			agentClient, err := net.Dial("unix", sshAgentSock)
-			if err != nil {
-				fmt.Println("Cannot connect to ssh-agent:", err)
-				os.Exit(1)
-			}
-
-			signers := agent.NewClient(agentClient).Signers
-			authMethods = append(authMethods, ssh.PublicKeysCallback(signers))
-		}
-	}
+// GetProxyJump returns the ProxyJump configured to this host in ~/.ssh/config.
+func (config *SSHConfig) GetProxyJump() string {
+	return config.hostConfig.ProxyJump
+}
 
-	keyPairs := make([]string, 0, 3)
+func (config *SSHConfig) getKeyPairs() []string {
+	keyPairs := make([]string, 0, len(config.hostConfig.IdentityFiles)+5)
 	if !strings.EqualFold("", config.server.KeyPair) {
 		keyPairs = append(keyPairs, config.server.KeyPair)
 	}
-	keyPairs = append(keyPairs, "id_rsa", "id_dsa")
+	keyPairs = append(keyPairs, config.hostConfig.IdentityFiles...)
 
-	for _, keyPair := range keyPairs {
-		if !strings.HasPrefix(keyPair, "/") {
-			keyPair = config.homeDir + "/.ssh/" + keyPair
+	return append(keyPairs, "id_rsa", "id_ecdsa", "id_ed25519", "id_dsa")
+}
+
+func (config *SSHConfig) getLocalSigners() []ssh.Signer {
+	signers := make([]ssh.Signer, 0, 5)
+
+	sshAgentSock := os.Getenv("SSH_AUTH_SOCK")
+	if !strings.EqualFold("", sshAgentSock) {
+		agentClient, err := net.Dial("unix", sshAgentSock)
+		if err != nil {
+			fmt.Println("Cannot connect to ssh-agent:", err)
+			os.Exit(1)
 		}
 
-		var (
-			pem []byte
-			err error
-		)
-
-		if config.client == nil {
-			pem, err = ioutil.ReadFile(keyPair)
-		} else {
-			content, _ := config.runCommand("cat " + keyPair)
-			contentBuf := bytes.NewBufferString(content)
-			pem, err = ioutil.ReadAll(contentBuf)
+		agentSigners, err := agent.NewClient(agentClient).Signers()
+		if err == nil {
+			signers = append(signers, agentSigners...)
 		}
+	}
+
+	for _, keyPair := range config.getKeyPairs() {
+		keyPair = expandIdentityFile(keyPair, config.homeDir, config.currentUser, config.GetHost(), config.GetUser())
 
+		pem, err := ioutil.ReadFile(keyPair)
 		if err != nil {
 			continue
 		}
@@ -156,10 +157,55 @@ func (config *SSHConfig) GetAuthMethods() []ssh.AuthMethod {
 			continue
 		}
 
-		authMethods = append(authMethods, ssh.PublicKeys(key))
+		signers = append(signers, key)
+	}
+
+	return signers
+}
+
+func (config *SSHConfig) getRemoteSigners() []ssh.Signer {
+	signers := make([]ssh.Signer, 0, 3)
+	remoteHost := getRemoteHost(config.client)
+
+	keyPairs := []string{"id_rsa", "id_dsa"}
+	if !strings.EqualFold("", config.server.KeyPair) {
+		keyPairs = append([]string{config.server.KeyPair}, keyPairs...)
+	}
+
+	for _, keyPair := range keyPairs {
+		if !strings.HasPrefix(keyPair, "/") {
+			keyPair = remoteHost.getHome() + "/.ssh/" + keyPair
+		}
+
+		content := remoteHost.readFile(keyPair)
+		if len(content) == 0 {
+			continue
+		}
+
+		key, err := ssh.ParsePrivateKey(content)
+		if err != nil {
+			continue
+		}
+
+		signers = append(signers, key)
 	}
 
-	return authMethods
+	return signers
+}
+
+func (config *SSHConfig) GetAuthMethods() []ssh.AuthMethod {
+	// All keys are offered by the same method, ssh client tries each method
+	// just once. Keys are read only when server asks for them.
+	return []ssh.AuthMethod{
+		ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+			signers := config.getLocalSigners()
+			if config.client != nil {
+				signers = append(signers, config.getRemoteSigners()...)
+			}
+
+			return signers, nil
+		}),
+	}
 }
 
 func (config *SSHConfig) GetSSHClientConfig() *ssh.ClientConfig {
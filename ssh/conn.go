@@ -11,14 +11,35 @@ import (
 	"golang.org/x/crypto/ssh"
 )
 
-func localConnect(server deploy.ServerConfig, verbose bool) (*ssh.Client, error) {
-	sshConfig := NewLocalSSHConfig(server)
+// maxJumpHosts avoids looping forever when ProxyJump in ~/.ssh/config points
+// to itself.
+const maxJumpHosts = 16
 
+// dial connects to the server of sshConfig directly or, if via is not nil,
+// through it.
+func dial(via *ssh.Client, sshConfig *SSHConfig, verbose bool) (*ssh.Client, error) {
 	if verbose {
 		fmt.Printf("Trying to connect to '%s@%s'...", sshConfig.GetUser(), sshConfig.GetURL())
 	}
 
-	client, err := ssh.Dial("tcp", sshConfig.GetURL(), sshConfig.GetSSHClientConfig())
+	client, err := func() (*ssh.Client, error) {
+		if via == nil {
+			return ssh.Dial("tcp", sshConfig.GetURL(), sshConfig.GetSSHClientConfig())
+		}
+
+		netConn, err := via.Dial("tcp", sshConfig.GetURL())
+		if err != nil {
+			return nil, err
+		}
+
+		conn, chans, reqs, err := ssh.NewClientConn(netConn, sshConfig.GetURL(), sshConfig.GetSSHClientConfig())
+		if err != nil {
+			netConn.Close()
+			return nil, err
+		}
+
+		return ssh.NewClient(conn, chans, reqs), nil
+	}()
 	if err != nil {
 		if verbose {
 			fmt.Println(" FAIL")
@@ -33,64 +54,58 @@ func localConnect(server deploy.ServerConfig, verbose bool) (*ssh.Client, error)
 	return client, nil
 }
 
-func Connect(env *deploy.Environment, remoteHost string, verbose bool) *ssh.Client {
-	if !env.HasBastion() {
-		client, err := localConnect(deploy.ServerConfig{
-			Host:    remoteHost,
-			User:    env.ECSHost.User,
-			KeyPair: env.ECSHost.KeyPair,
-		}, verbose)
-
-		if err != nil {
-			fmt.Println("Error connecting to remote server:\n-", err)
-			os.Exit(1)
-		}
-
-		return client
+// expandProxyJump returns the hops configured as ProxyJump of server in
+// ~/.ssh/config, including the ones needed to reach the first of them.
+func expandProxyJump(server deploy.ServerConfig, depth int) []deploy.ServerConfig {
+	proxyJump := GetUserConfig().Lookup(server.Host).ProxyJump
+	if strings.EqualFold("", proxyJump) {
+		return nil
 	}
 
-	client, err := localConnect(env.Bastion, verbose)
-	if err != nil {
-		fmt.Println("Error connecting to remote server:\n-", err)
+	if depth >= maxJumpHosts {
+		fmt.Printf("Too many ProxyJump hops to reach '%s', check your ~/.ssh/config\n", server.Host)
 		os.Exit(1)
 	}
 
-	// Try to connect to remoteHost over bastion
-	sshConfig := NewRemoteSSHConfig(client, deploy.ServerConfig{
-		Host:    remoteHost,
-		User:    env.ECSHost.User,
-		KeyPair: env.ECSHost.KeyPair,
-	})
-
-	if verbose {
-		fmt.Printf("Trying to connect to '%s@%s'...", sshConfig.GetUser(), sshConfig.GetURL())
+	jumpHosts := deploy.ParseProxyJump(proxyJump)
+	if len(jumpHosts) == 0 {
+		return nil
 	}
 
-	netConn, err := client.Dial("tcp", sshConfig.GetURL())
-	if err != nil {
-		if verbose {
-			fmt.Println(" FAIL")
-		}
+	return append(expandProxyJump(jumpHosts[0], depth+1), jumpHosts...)
+}
 
-		fmt.Println("Error connecting to remote server:\n-", err)
-		os.Exit(1)
+// GetJumpHosts returns every hop between this machine and remoteHost. Hops
+// configured on the environment take precedence over ProxyJump from
+// ~/.ssh/config, which is only followed from the first hop.
+func GetJumpHosts(env *deploy.Environment, remoteHost string) []deploy.ServerConfig {
+	jumpHosts := env.GetJumpHosts()
+
+	first := deploy.ServerConfig{Host: remoteHost}
+	if len(jumpHosts) > 0 {
+		first = jumpHosts[0]
 	}
 
-	conn, chans, reqs, err := ssh.NewClientConn(netConn, sshConfig.GetHost(), sshConfig.GetSSHClientConfig())
-	if err != nil {
-		if verbose {
-			fmt.Println(" FAIL")
-		}
+	return append(expandProxyJump(first, 0), jumpHosts...)
+}
 
+// Connect returns a connection to remoteHost shared with the rest of the
+// command, it must not be closed by the caller.
+func Connect(env *deploy.Environment, remoteHost string, verbose bool) *ssh.Client {
+	client, err := defaultPool.Connect(env, remoteHost, verbose)
+	if err != nil {
 		fmt.Println("Error connecting to remote server:\n-", err)
 		os.Exit(1)
 	}
 
-	if verbose {
-		fmt.Println(" OK")
-	}
+	return client
+}
 
-	return ssh.NewClient(conn, chans, reqs)
+// TryConnect opens the shared connection to remoteHost, unlike Connect it
+// returns the error so the caller can look for another host.
+func TryConnect(env *deploy.Environment, remoteHost string) error {
+	_, err := defaultPool.Connect(env, remoteHost, false)
+	return err
 }
 
 func RunCommand(session *ssh.Session, command string) error {
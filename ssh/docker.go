@@ -15,7 +15,6 @@ import (
 
 func DockerLogs(env *deploy.Environment, remoteHost, containerID, tail string, follow bool) {
 	client := Connect(env, remoteHost, true)
-	defer client.Close()
 
 	sess, err := client.NewSession()
 	if err != nil {
@@ -42,7 +41,6 @@ func DockerLogs(env *deploy.Environment, remoteHost, containerID, tail string, f
 
 func DockerExec(env *deploy.Environment, remoteHost, containerID, command string) {
 	client := Connect(env, remoteHost, true)
-	defer client.Close()
 
 	sess, err := client.NewSession()
 	if err != nil {
@@ -76,14 +74,17 @@ func DockerExec(env *deploy.Environment, remoteHost, containerID, command string
 	RunCommand(sess, command)
 }
 
+// GetContainers asks ECS Agent of remoteHost which containers belong to
+// taskArn, it's safe to be called concurrently.
 func GetContainers(env *deploy.Environment, remoteHost, taskArn string) ([]deploy.Container, error) {
-	client := Connect(env, remoteHost, false)
-	defer client.Close()
+	client, err := defaultPool.Connect(env, remoteHost, false)
+	if err != nil {
+		return nil, err
+	}
 
 	sess, err := client.NewSession()
 	if err != nil {
-		fmt.Println("Cannot get session:", err)
-		os.Exit(1)
+		return nil, fmt.Errorf("cannot get session: %s", err)
 	}
 
 	defer sess.Close()
@@ -95,8 +96,7 @@ func GetContainers(env *deploy.Environment, remoteHost, taskArn string) ([]deplo
 
 	err = RunCommand(sess, command)
 	if err != nil {
-		fmt.Printf("Error running \"%s\":\n- %s\n", command, err)
-		os.Exit(1)
+		return nil, fmt.Errorf("error running \"%s\": %s", command, err)
 	}
 
 	var agentResp struct {
@@ -108,8 +108,7 @@ func GetContainers(env *deploy.Environment, remoteHost, taskArn string) ([]deplo
 
 	err = json.Unmarshal(stdout.Bytes(), &agentResp)
 	if err != nil {
-		fmt.Println("Cannot read response from ECS Agent:", err)
-		os.Exit(1)
+		return nil, fmt.Errorf("cannot read response from ECS Agent: %s", err)
 	}
 
 	if len(agentResp.Containers) == 0 {
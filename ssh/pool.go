@@ -0,0 +1,189 @@
+package ssh
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	deploy "github.com/guilherme-santos/deploy-ecs"
+	"golang.org/x/crypto/ssh"
+)
+
+type (
+	// Pool keeps one connection per hop during the life of a command, hosts
+	// reached through the same bastion share the connection to it. It's safe
+	// to be used by several goroutines.
+	Pool struct {
+		mutex   sync.Mutex
+		clients map[string]*pooledClient
+	}
+
+	pooledClient struct {
+		once   sync.Once
+		client *ssh.Client
+		err    error
+
+		// Information read from this host when it's used as jump host
+		homeOnce  sync.Once
+		home      string
+		filesLock sync.Mutex
+		files     map[string][]byte
+	}
+)
+
+var (
+	defaultPool = NewPool()
+
+	remoteHostsLock sync.Mutex
+	remoteHosts     = make(map[*ssh.Client]*pooledClient)
+)
+
+func NewPool() *Pool {
+	return &Pool{
+		clients: make(map[string]*pooledClient),
+	}
+}
+
+// Connect returns a client to remoteHost, every hop needed to reach it is
+// dialed just once and reused by the next calls.
+func (pool *Pool) Connect(env *deploy.Environment, remoteHost string, verbose bool) (*ssh.Client, error) {
+	servers := append(GetJumpHosts(env, remoteHost), deploy.ServerConfig{
+		Host:    remoteHost,
+		Port:    env.ECSHost.Port,
+		User:    env.ECSHost.User,
+		KeyPair: env.ECSHost.KeyPair,
+	})
+
+	var (
+		client *ssh.Client
+		keys   = make([]string, 0, len(servers))
+	)
+
+	for _, server := range servers {
+		var sshConfig *SSHConfig
+		if client == nil {
+			sshConfig = NewLocalSSHConfig(server)
+		} else {
+			sshConfig = NewRemoteSSHConfig(client, server)
+		}
+
+		// Same host reached by another path is a different connection
+		keys = append(keys, sshConfig.GetUser()+"@"+sshConfig.GetURL())
+		entry := pool.get(strings.Join(keys, ","))
+
+		via := client
+		entry.once.Do(func() {
+			entry.client, entry.err = dial(via, sshConfig, verbose)
+			if entry.err == nil {
+				remoteHostsLock.Lock()
+				remoteHosts[entry.client] = entry
+				remoteHostsLock.Unlock()
+			}
+		})
+
+		if entry.err != nil {
+			return nil, entry.err
+		}
+
+		client = entry.client
+	}
+
+	return client, nil
+}
+
+func (pool *Pool) get(key string) *pooledClient {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	entry, ok := pool.clients[key]
+	if !ok {
+		entry = &pooledClient{
+			files: make(map[string][]byte),
+		}
+		pool.clients[key] = entry
+	}
+
+	return entry
+}
+
+// Close closes every connection, the last hops first.
+func (pool *Pool) Close() {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	keys := make([]string, 0, len(pool.clients))
+	for key := range pool.clients {
+		keys = append(keys, key)
+	}
+
+	// Longer chains are deeper hops, they need to be closed before the hop
+	// they are using
+	sort.Slice(keys, func(i, j int) bool {
+		return strings.Count(keys[i], ",") > strings.Count(keys[j], ",")
+	})
+
+	for _, key := range keys {
+		entry := pool.clients[key]
+		if entry.client == nil {
+			continue
+		}
+
+		remoteHostsLock.Lock()
+		delete(remoteHosts, entry.client)
+		remoteHostsLock.Unlock()
+
+		entry.client.Close()
+	}
+
+	pool.clients = make(map[string]*pooledClient)
+}
+
+// CloseConnections closes all connections opened by Connect.
+func CloseConnections() {
+	defaultPool.Close()
+}
+
+// getRemoteHost returns the pool entry of client, connections that were not
+// opened by a pool get a new one so information read from them is cached too.
+func getRemoteHost(client *ssh.Client) *pooledClient {
+	remoteHostsLock.Lock()
+	defer remoteHostsLock.Unlock()
+
+	entry, ok := remoteHosts[client]
+	if !ok {
+		entry = &pooledClient{
+			client: client,
+			files:  make(map[string][]byte),
+		}
+		remoteHosts[client] = entry
+	}
+
+	return entry
+}
+
+func (entry *pooledClient) getHome() string {
+	entry.homeOnce.Do(func() {
+		entry.home, _ = runCommand(entry.client, "echo $HOME")
+	})
+
+	return entry.home
+}
+
+// readFile reads filename from this host just once, files that cannot be
+// read are cached as empty.
+func (entry *pooledClient) readFile(filename string) []byte {
+	entry.filesLock.Lock()
+	defer entry.filesLock.Unlock()
+
+	content, ok := entry.files[filename]
+	if !ok {
+		output, err := runCommand(entry.client, "cat "+filename)
+		if err == nil {
+			content = []byte(output)
+		}
+
+		entry.files[filename] = content
+	}
+
+	return content
+}
@@ -0,0 +1,323 @@
+package ssh
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// maxIncludeDepth is the same limit used by OpenSSH to avoid include loops.
+const maxIncludeDepth = 16
+
+type (
+	// UserConfig keeps the Host blocks read from ~/.ssh/config, just the
+	// keywords understood by deploy-ecs are stored.
+	UserConfig struct {
+		hosts []hostBlock
+	}
+
+	hostBlock struct {
+		patterns []string
+		options  map[string][]string
+	}
+
+	// HostConfig is the result of applying every matching Host block to a host.
+	HostConfig struct {
+		HostName      string
+		User          string
+		Port          string
+		IdentityFiles []string
+		ProxyJump     string
+	}
+)
+
+var (
+	userConfig     *UserConfig
+	userConfigOnce sync.Once
+
+	supportedKeywords = map[string]struct{}{
+		"hostname":     {},
+		"user":         {},
+		"port":         {},
+		"identityfile": {},
+		"proxyjump":    {},
+	}
+)
+
+// GetUserConfig loads ~/.ssh/config only once, a missing file is the same as
+// an empty one.
+func GetUserConfig() *UserConfig {
+	userConfigOnce.Do(func() {
+		userConfig = &UserConfig{}
+
+		currentUser, err := user.Current()
+		if err != nil {
+			return
+		}
+
+		filename := filepath.Join(currentUser.HomeDir, ".ssh", "config")
+		if err := userConfig.parseFile(filename, currentUser.HomeDir, 0); err != nil && !os.IsNotExist(err) {
+			fmt.Printf("Cannot read '%s': %s\n", filename, err)
+			os.Exit(1)
+		}
+	})
+
+	return userConfig
+}
+
+func (config *UserConfig) parseFile(filename, homeDir string, depth int) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+
+		keyword, args := splitConfigLine(scanner.Text())
+		if strings.EqualFold("", keyword) {
+			continue
+		}
+
+		switch keyword {
+		case "host":
+			config.hosts = append(config.hosts, hostBlock{
+				patterns: args,
+				options:  make(map[string][]string),
+			})
+		case "match":
+			// Match blocks are not supported, options inside them are ignored
+			config.hosts = append(config.hosts, hostBlock{
+				options: make(map[string][]string),
+			})
+		case "include":
+			if depth >= maxIncludeDepth {
+				return fmt.Errorf("%s:%d: too many nested includes", filename, lineNumber)
+			}
+
+			for _, pattern := range args {
+				pattern = expandTilde(pattern, homeDir)
+				if !filepath.IsAbs(pattern) {
+					pattern = filepath.Join(homeDir, ".ssh", pattern)
+				}
+
+				files, _ := filepath.Glob(pattern)
+				for _, file := range files {
+					if err := config.parseFile(file, homeDir, depth+1); err != nil {
+						return err
+					}
+				}
+			}
+		default:
+			if _, ok := supportedKeywords[keyword]; !ok || len(args) == 0 {
+				continue
+			}
+
+			if len(config.hosts) == 0 {
+				// Options before the first Host are applied to all hosts
+				config.hosts = append(config.hosts, hostBlock{
+					patterns: []string{"*"},
+					options:  make(map[string][]string),
+				})
+			}
+
+			block := config.hosts[len(config.hosts)-1]
+			block.options[keyword] = append(block.options[keyword], args[0])
+		}
+	}
+
+	return scanner.Err()
+}
+
+// splitConfigLine returns the lower case keyword and its arguments, keyword
+// and arguments can be separated by spaces or "=" and arguments can be quoted.
+func splitConfigLine(line string) (string, []string) {
+	line = strings.TrimSpace(line)
+	if strings.EqualFold("", line) || strings.HasPrefix(line, "#") {
+		return "", nil
+	}
+
+	pos := strings.IndexAny(line, " \t=")
+	if pos < 0 {
+		return strings.ToLower(line), nil
+	}
+
+	keyword := strings.ToLower(line[:pos])
+	rest := strings.TrimLeft(line[pos:], " \t")
+	rest = strings.TrimPrefix(rest, "=")
+
+	args := make([]string, 0)
+
+	var (
+		current string
+		inQuote bool
+		hasArg  bool
+	)
+
+	for _, r := range rest {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			hasArg = true
+		case !inQuote && (r == ' ' || r == '\t'):
+			if hasArg {
+				args = append(args, current)
+				current = ""
+				hasArg = false
+			}
+		case !inQuote && r == '#' && !hasArg:
+			// Rest of the line is a comment
+			return keyword, args
+		default:
+			current += string(r)
+			hasArg = true
+		}
+	}
+
+	if hasArg {
+		args = append(args, current)
+	}
+
+	return keyword, args
+}
+
+// match follows Host of OpenSSH: patterns are separated by spaces or commas,
+// any negated one (!pattern) matching host rejects the whole block.
+func (block hostBlock) match(host string) bool {
+	var matched bool
+
+	host = strings.ToLower(host)
+
+	for _, pattern := range block.patterns {
+		for _, p := range strings.Split(pattern, ",") {
+			negate := strings.HasPrefix(p, "!")
+			p = strings.TrimPrefix(p, "!")
+
+			if strings.EqualFold("", p) || !matchPattern(strings.ToLower(p), host) {
+				continue
+			}
+			if negate {
+				return false
+			}
+
+			matched = true
+		}
+	}
+
+	return matched
+}
+
+// matchPattern is the same wildcard matching of OpenSSH, "*" is any sequence
+// and "?" any character. Unlike path.Match there are no character classes or
+// escapes, and "/" is a regular character.
+func matchPattern(pattern, value string) bool {
+	var (
+		p, v int
+
+		// where to retry when the last "*" needs to match one more character
+		starP = -1
+		starV int
+	)
+
+	for v < len(value) {
+		switch {
+		case p < len(pattern) && pattern[p] == '*':
+			starP = p
+			starV = v
+			p++
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == value[v]):
+			p++
+			v++
+		case starP >= 0:
+			starV++
+			p = starP + 1
+			v = starV
+		default:
+			return false
+		}
+	}
+
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+
+	return p == len(pattern)
+}
+
+// Lookup applies every Host block matching host, as OpenSSH does the first
+// value obtained for each keyword is the one used, except for IdentityFile
+// which accumulates.
+func (config *UserConfig) Lookup(host string) HostConfig {
+	var hostConfig HostConfig
+
+	if config == nil || strings.EqualFold("", host) {
+		return hostConfig
+	}
+
+	for _, block := range config.hosts {
+		if !block.match(host) {
+			continue
+		}
+
+		if values := block.options["hostname"]; len(values) > 0 && strings.EqualFold("", hostConfig.HostName) {
+			hostConfig.HostName = strings.Replace(values[0], "%h", host, -1)
+		}
+		if values := block.options["user"]; len(values) > 0 && strings.EqualFold("", hostConfig.User) {
+			hostConfig.User = values[0]
+		}
+		if values := block.options["port"]; len(values) > 0 && strings.EqualFold("", hostConfig.Port) {
+			hostConfig.Port = values[0]
+		}
+		if values := block.options["proxyjump"]; len(values) > 0 && strings.EqualFold("", hostConfig.ProxyJump) {
+			hostConfig.ProxyJump = values[0]
+		}
+
+		hostConfig.IdentityFiles = append(hostConfig.IdentityFiles, block.options["identityfile"]...)
+	}
+
+	if strings.EqualFold("none", hostConfig.ProxyJump) {
+		hostConfig.ProxyJump = ""
+	}
+
+	return hostConfig
+}
+
+// expandIdentityFile replaces "~" and the tokens %d, %u, %h and %r, relative
+// paths are looked up in ~/.ssh as deploy-ecs always did with key pairs.
+func expandIdentityFile(identityFile, homeDir, localUser, host, remoteUser string) string {
+	identityFile = expandTilde(identityFile, homeDir)
+
+	identityFile = strings.NewReplacer(
+		"%%", "%",
+		"%d", homeDir,
+		"%u", localUser,
+		"%h", host,
+		"%r", remoteUser,
+	).Replace(identityFile)
+
+	if !filepath.IsAbs(identityFile) {
+		identityFile = filepath.Join(homeDir, ".ssh", identityFile)
+	}
+
+	return identityFile
+}
+
+func expandTilde(filename, homeDir string) string {
+	if filename == "~" {
+		return homeDir
+	}
+	if strings.HasPrefix(filename, "~/") {
+		return filepath.Join(homeDir, filename[2:])
+	}
+
+	return filename
+}
@@ -0,0 +1,191 @@
+package ssh
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSplitConfigLine(t *testing.T) {
+	tests := []struct {
+		line    string
+		keyword string
+		args    []string
+	}{
+		{line: "", keyword: ""},
+		{line: "  # comment", keyword: ""},
+		{line: "Host bastion *.internal", keyword: "host", args: []string{"bastion", "*.internal"}},
+		{line: "\tHostName\t10.0.0.1", keyword: "hostname", args: []string{"10.0.0.1"}},
+		{line: "Port=2222", keyword: "port", args: []string{"2222"}},
+		{line: "User = admin", keyword: "user", args: []string{"admin"}},
+		{line: `IdentityFile "~/.ssh/my key"`, keyword: "identityfile", args: []string{"~/.ssh/my key"}},
+		{line: "ProxyJump jump # comment", keyword: "proxyjump", args: []string{"jump"}},
+		{line: "Compression", keyword: "compression", args: []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.line, func(t *testing.T) {
+			keyword, args := splitConfigLine(test.line)
+			if keyword != test.keyword {
+				t.Errorf("got keyword %q, want %q", keyword, test.keyword)
+			}
+			if len(args) != len(test.args) || (len(args) > 0 && !reflect.DeepEqual(args, test.args)) {
+				t.Errorf("got args %q, want %q", args, test.args)
+			}
+		})
+	}
+}
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		value   string
+		want    bool
+	}{
+		{pattern: "*", value: "anything", want: true},
+		{pattern: "bastion", value: "bastion", want: true},
+		{pattern: "bastion", value: "bastion2", want: false},
+		{pattern: "*.internal", value: "web.internal", want: true},
+		{pattern: "*.internal", value: "internal", want: false},
+		{pattern: "web-?", value: "web-1", want: true},
+		{pattern: "web-?", value: "web-12", want: false},
+		{pattern: "a*b*c", value: "axxbyyc", want: true},
+		{pattern: "a*b*c", value: "axxbyy", want: false},
+		{pattern: "10.0.*", value: "10.0.1.2", want: true},
+		{pattern: "[ab]", value: "a", want: false},
+		{pattern: "[ab]", value: "[ab]", want: true},
+		{pattern: "dir/*", value: "dir/sub/host", want: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.pattern+" "+test.value, func(t *testing.T) {
+			if got := matchPattern(test.pattern, test.value); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestHostBlockMatch(t *testing.T) {
+	block := hostBlock{patterns: []string{"*.internal,!db.internal", "Bastion"}}
+
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{host: "web.internal", want: true},
+		{host: "WEB.Internal", want: true},
+		{host: "db.internal", want: false},
+		{host: "bastion", want: true},
+		{host: "other", want: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.host, func(t *testing.T) {
+			if got := block.match(test.host); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	if (hostBlock{patterns: []string{"!db"}}).match("web") {
+		t.Errorf("a block with only negated patterns should not match")
+	}
+}
+
+func TestUserConfigLookup(t *testing.T) {
+	homeDir, err := ioutil.TempDir("", "deploy-ecs-ssh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(homeDir)
+
+	sshDir := filepath.Join(homeDir, ".ssh")
+	os.MkdirAll(filepath.Join(sshDir, "config.d"), 0700)
+
+	writeFile(t, filepath.Join(sshDir, "config"), `
+IdentityFile ~/.ssh/global
+
+Include config.d/*
+
+Host bastion
+    HostName 203.0.113.10
+    User jump
+
+Host *.internal !db.internal
+    ProxyJump bastion
+    User ec2-user
+
+Match host *
+    User ignored
+
+Host *
+    Port 22
+    User default
+    IdentityFile ~/.ssh/default
+`)
+	writeFile(t, filepath.Join(sshDir, "config.d", "work"), `
+Host web-?.internal
+    HostName %h.example.com
+    Port 2222
+`)
+
+	config := &UserConfig{}
+	if err := config.parseFile(filepath.Join(sshDir, "config"), homeDir, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		host string
+		want HostConfig
+	}{
+		{
+			host: "bastion",
+			want: HostConfig{HostName: "203.0.113.10", User: "jump", Port: "22", IdentityFiles: []string{"~/.ssh/global", "~/.ssh/default"}},
+		},
+		{
+			host: "web-1.internal",
+			want: HostConfig{HostName: "web-1.internal.example.com", User: "ec2-user", Port: "2222", ProxyJump: "bastion", IdentityFiles: []string{"~/.ssh/global", "~/.ssh/default"}},
+		},
+		{
+			host: "api.internal",
+			want: HostConfig{User: "ec2-user", Port: "22", ProxyJump: "bastion", IdentityFiles: []string{"~/.ssh/global", "~/.ssh/default"}},
+		},
+		{
+			host: "db.internal",
+			want: HostConfig{User: "default", Port: "22", IdentityFiles: []string{"~/.ssh/global", "~/.ssh/default"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.host, func(t *testing.T) {
+			if got := config.Lookup(test.host); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestUserConfigIncludeLoop(t *testing.T) {
+	homeDir, err := ioutil.TempDir("", "deploy-ecs-ssh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(homeDir)
+
+	os.MkdirAll(filepath.Join(homeDir, ".ssh"), 0700)
+	writeFile(t, filepath.Join(homeDir, ".ssh", "config"), "Include config\n")
+
+	config := &UserConfig{}
+	if err := config.parseFile(filepath.Join(homeDir, ".ssh", "config"), homeDir, 0); err == nil {
+		t.Errorf("include loop should return an error")
+	}
+}
+
+func writeFile(t *testing.T, filename, content string) {
+	if err := ioutil.WriteFile(filename, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}